@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/types/uuid"
+)
+
+//encore:api private method=POST path=/api/access-token
+func CreateAccessToken(ctx context.Context, params db.CreateAccessTokenParams) (*db.AccessToken, error) {
+	return db.New().CreateAccessToken(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=GET path=/api/access-token/hash/:tokenHash
+func GetAccessTokenByHash(ctx context.Context, tokenHash string) (*db.AccessToken, error) {
+	return db.New().GetAccessTokenByHash(ctx, markblogdb.Stdlib(), tokenHash)
+}
+
+type GetAccessTokensForUserResult struct {
+	Tokens []db.AccessToken `json:"tokens"`
+}
+
+//encore:api private method=GET path=/api/access-token/user/:userID
+func GetAccessTokensForUser(ctx context.Context, userID uuid.UUID) (*GetAccessTokensForUserResult, error) {
+	rows, err := db.New().GetAccessTokensForUser(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetAccessTokensForUserResult{
+		Tokens: make([]db.AccessToken, 0),
+	}
+	for _, r := range rows {
+		res.Tokens = append(res.Tokens, *r)
+	}
+
+	return res, nil
+}
+
+//encore:api private method=POST path=/api/access-token/touch/:id
+func TouchAccessToken(ctx context.Context, id uuid.UUID) error {
+	return db.New().TouchAccessToken(ctx, markblogdb.Stdlib(), id)
+}
+
+type DeleteAccessTokenForUserResult struct {
+	Deleted bool `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/access-token/delete
+func DeleteAccessTokenForUser(ctx context.Context, params db.DeleteAccessTokenForUserParams) (*DeleteAccessTokenForUserResult, error) {
+	n, err := db.New().DeleteAccessTokenForUser(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteAccessTokenForUserResult{Deleted: n > 0}, nil
+}
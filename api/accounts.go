@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+)
+
+//encore:api private method=POST path=/api/account-deletion
+func ScheduleAccountDeletion(ctx context.Context, params db.ScheduleAccountDeletionParams) (*db.AccountDeletion, error) {
+	return db.New().ScheduleAccountDeletion(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=GET path=/api/account-deletion/user/:userID
+func GetAccountDeletionForUser(ctx context.Context, userID uuid.UUID) (*db.AccountDeletion, error) {
+	return db.New().GetAccountDeletionForUser(ctx, markblogdb.Stdlib(), userID)
+}
+
+type CancelAccountDeletionResult struct {
+	Cancelled bool `json:"cancelled"`
+}
+
+//encore:api private method=POST path=/api/account-deletion/cancel/:userID
+func CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (*CancelAccountDeletionResult, error) {
+	n, err := db.New().CancelAccountDeletion(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return &CancelAccountDeletionResult{Cancelled: n > 0}, nil
+}
+
+var _ = cron.NewJob("process-account-deletions", cron.JobConfig{
+	Title:    "Delete accounts whose grace period has ended",
+	Every:    1 * cron.Hour,
+	Endpoint: ProcessAccountDeletions,
+})
+
+type ProcessAccountDeletionsResult struct {
+	Deleted int `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/account-deletion/process
+func ProcessAccountDeletions(ctx context.Context) (*ProcessAccountDeletionsResult, error) {
+	due, err := db.New().GetDueAccountDeletions(ctx, markblogdb.Stdlib(), 100)
+	if err != nil {
+		return nil, err
+	}
+
+	res := new(ProcessAccountDeletionsResult)
+	for _, d := range due {
+		if err := deleteAccount(ctx, d); err != nil {
+			rlog.Error("account deletion failed", "user_id", d.UserID, "err", err)
+			continue
+		}
+		res.Deleted++
+	}
+
+	return res, nil
+}
+
+// deleteAccount removes the user in d. Posts go with the user through the
+// foreign key cascade; comments are either removed too or left behind with
+// their author nulled out, depending on what the user asked for.
+func deleteAccount(ctx context.Context, d *db.AccountDeletion) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	if !d.KeepComments {
+		if err := q.DeleteCommentsByUser(ctx, tx, &d.UserID); err != nil {
+			return err
+		}
+	}
+	if err := q.DeleteUser(ctx, tx, d.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
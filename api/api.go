@@ -39,6 +39,11 @@ func CheckUserExists(ctx context.Context, username string) (*CheckUserExistsResu
 	return res, err
 }
 
+//encore:api private method=POST path=/api/user/password
+func UpdateUserPassword(ctx context.Context, params db.UpdateUserPasswordParams) error {
+	return db.New().UpdateUserPassword(ctx, markblogdb.Stdlib(), params)
+}
+
 type GetLatestUserActivityResult struct {
 	Activity []db.GetLatestUserActivityRow
 }
@@ -69,6 +74,18 @@ func GetPostByID(ctx context.Context, id uuid.UUID) (*db.Post, error) {
 	return db.New().GetPostByID(ctx, markblogdb.Stdlib(), id)
 }
 
+type CountPostsByUserResult struct {
+	Count int64 `json:"count"`
+}
+
+//encore:api private method=GET path=/api/post/count/:userID
+func CountPostsByUser(ctx context.Context, userID uuid.UUID) (*CountPostsByUserResult, error) {
+	res := new(CountPostsByUserResult)
+	var err error
+	res.Count, err = db.New().CountPostsByUser(ctx, markblogdb.Stdlib(), userID)
+	return res, err
+}
+
 type GetLatestPostsResult struct {
 	Posts []db.GetLatestPostsRow `json:"posts"`
 }
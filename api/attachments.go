@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"encore.dev/storage/objects"
+	"encore.dev/types/uuid"
+)
+
+// AttachmentBucket holds uploaded files. Each is stored under its ID, and
+// large images also under ID-thumb.
+var AttachmentBucket = objects.NewBucket("attachments", objects.BucketConfig{
+	Public: true,
+})
+
+func thumbnailKey(id uuid.UUID) string {
+	return id.String() + "-thumb"
+}
+
+// removeAttachmentObjects deletes the stored files of an attachment.
+// Objects that are already gone are not an error.
+func removeAttachmentObjects(ctx context.Context, id uuid.UUID, hasThumbnail bool) error {
+	keys := []string{id.String()}
+	if hasThumbnail {
+		keys = append(keys, thumbnailKey(id))
+	}
+	for _, key := range keys {
+		if err := AttachmentBucket.Remove(ctx, key); err != nil && !errors.Is(err, objects.ErrObjectNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+//encore:api private method=POST path=/api/attachment
+func CreateAttachment(ctx context.Context, params db.CreateAttachmentParams) (*db.Attachment, error) {
+	return db.New().CreateAttachment(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=GET path=/api/attachment/id/:id
+func GetAttachmentByID(ctx context.Context, id uuid.UUID) (*db.Attachment, error) {
+	return db.New().GetAttachmentByID(ctx, markblogdb.Stdlib(), id)
+}
+
+//encore:api private method=POST path=/api/attachment/delete/:id
+func DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	return db.New().DeleteAttachment(ctx, markblogdb.Stdlib(), id)
+}
+
+type AttachmentsResult struct {
+	Attachments []db.Attachment `json:"attachments"`
+}
+
+//encore:api private method=GET path=/api/post/attachments/:postID
+func GetPostAttachments(ctx context.Context, postID uuid.UUID) (*AttachmentsResult, error) {
+	rows, err := db.New().GetPostAttachments(ctx, markblogdb.Stdlib(), postID)
+	if err != nil {
+		return nil, err
+	}
+	res := &AttachmentsResult{
+		Attachments: make([]db.Attachment, 0),
+	}
+	for _, r := range rows {
+		res.Attachments = append(res.Attachments, *r)
+	}
+
+	return res, nil
+}
+
+type SetPostAttachmentsParams struct {
+	PostID        uuid.UUID
+	AttachmentIDs []uuid.UUID
+}
+
+// SetPostAttachments replaces the attachments of a post, keeping them in
+// the given order.
+//
+//encore:api private method=POST path=/api/post/attachments
+func SetPostAttachments(ctx context.Context, params SetPostAttachmentsParams) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	if err := q.DeletePostAttachments(ctx, tx, params.PostID); err != nil {
+		return err
+	}
+
+	for i, attachmentID := range params.AttachmentIDs {
+		if err := q.CreatePostAttachment(ctx, tx, db.CreatePostAttachmentParams{
+			PostID:       params.PostID,
+			AttachmentID: attachmentID,
+			Position:     int32(i),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// orphanedAttachmentAge is how long an attachment no post embeds is kept,
+// giving the uploader time to finish the post.
+const orphanedAttachmentAge = 24 * time.Hour
+
+var _ = cron.NewJob("purge-orphaned-attachments", cron.JobConfig{
+	Title:    "Delete attachments that no post embeds",
+	Every:    1 * cron.Hour,
+	Endpoint: PurgeOrphanedAttachments,
+})
+
+type PurgeOrphanedAttachmentsResult struct {
+	Deleted int `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/attachment/purge-orphaned
+func PurgeOrphanedAttachments(ctx context.Context) (*PurgeOrphanedAttachmentsResult, error) {
+	q := db.New()
+	orphaned, err := q.GetOrphanedAttachments(ctx, markblogdb.Stdlib(), db.GetOrphanedAttachmentsParams{
+		CreatedAt: time.Now().Add(-orphanedAttachmentAge),
+		Limit:     500,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := new(PurgeOrphanedAttachmentsResult)
+	for _, a := range orphaned {
+		if err := removeAttachmentObjects(ctx, a.ID, a.HasThumbnail); err != nil {
+			rlog.Error("attachment removal failed", "attachment_id", a.ID, "err", err)
+			continue
+		}
+		if err := q.DeleteAttachment(ctx, markblogdb.Stdlib(), a.ID); err != nil {
+			return res, err
+		}
+		res.Deleted++
+	}
+	return res, nil
+}
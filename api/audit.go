@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+)
+
+// auditRetentionKey is the site setting holding how many days audit events
+// are kept. defaultAuditRetentionDays applies while it is unset.
+const (
+	auditRetentionKey         = "audit_retention_days"
+	defaultAuditRetentionDays = 90
+)
+
+//encore:api private method=POST path=/api/audit
+func CreateAuditEvent(ctx context.Context, params db.CreateAuditEventParams) error {
+	return db.New().CreateAuditEvent(ctx, markblogdb.Stdlib(), params)
+}
+
+type AuditEventsResult struct {
+	Events []db.AuditEvent `json:"events"`
+}
+
+//encore:api private method=GET path=/api/audit
+func GetAuditEvents(ctx context.Context, params db.GetAuditEventsParams) (*AuditEventsResult, error) {
+	rows, err := db.New().GetAuditEvents(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &AuditEventsResult{
+		Events: make([]db.AuditEvent, 0),
+	}
+	for _, r := range rows {
+		res.Events = append(res.Events, *r)
+	}
+
+	return res, nil
+}
+
+type GetRecentAuditEventsForUserParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+//encore:api private method=GET path=/api/audit/user
+func GetRecentAuditEventsForUser(ctx context.Context, params GetRecentAuditEventsForUserParams) (*AuditEventsResult, error) {
+	rows, err := db.New().GetRecentAuditEventsForUser(ctx, markblogdb.Stdlib(), db.GetRecentAuditEventsForUserParams{
+		UserID: &params.UserID,
+		Limit:  params.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &AuditEventsResult{
+		Events: make([]db.AuditEvent, 0),
+	}
+	for _, r := range rows {
+		res.Events = append(res.Events, *r)
+	}
+
+	return res, nil
+}
+
+var _ = cron.NewJob("prune-audit-events", cron.JobConfig{
+	Title:    "Delete audit events past their retention period",
+	Every:    24 * cron.Hour,
+	Endpoint: PruneAuditEvents,
+})
+
+type PruneAuditEventsResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/audit/prune
+func PruneAuditEvents(ctx context.Context) (*PruneAuditEventsResult, error) {
+	q := db.New()
+
+	days := defaultAuditRetentionDays
+	value, err := q.GetSiteSetting(ctx, markblogdb.Stdlib(), auditRetentionKey)
+	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			days = n
+		} else {
+			rlog.Warn("ignoring invalid audit retention setting", "value", value)
+		}
+	}
+
+	res := new(PruneAuditEventsResult)
+	res.Deleted, err = q.DeleteAuditEventsBefore(ctx, markblogdb.Stdlib(), time.Now().AddDate(0, 0, -days))
+	return res, err
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+)
+
+type SpendChallengeResult struct {
+	Spent bool `json:"spent"`
+}
+
+// SpendChallenge records a solved proof-of-work challenge. Spent is false if
+// it had already been used.
+//
+//encore:api private method=POST path=/api/challenge/spend
+func SpendChallenge(ctx context.Context, params db.SpendChallengeParams) (*SpendChallengeResult, error) {
+	n, err := db.New().SpendChallenge(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &SpendChallengeResult{Spent: n > 0}, nil
+}
+
+type CountChallengesSpentSinceResult struct {
+	Count int64 `json:"count"`
+}
+
+//encore:api private method=GET path=/api/challenge/count
+func CountChallengesSpentSince(ctx context.Context, params db.CountChallengesSpentSinceParams) (*CountChallengesSpentSinceResult, error) {
+	res := new(CountChallengesSpentSinceResult)
+	var err error
+	res.Count, err = db.New().CountChallengesSpentSince(ctx, markblogdb.Stdlib(), params)
+	return res, err
+}
+
+var _ = cron.NewJob("delete-expired-challenges", cron.JobConfig{
+	Title:    "Forget expired proof-of-work challenges",
+	Every:    1 * cron.Hour,
+	Endpoint: DeleteExpiredChallenges,
+})
+
+type DeleteExpiredChallengesResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/challenge/delete-expired
+func DeleteExpiredChallenges(ctx context.Context) (*DeleteExpiredChallengesResult, error) {
+	res := new(DeleteExpiredChallengesResult)
+	var err error
+	res.Deleted, err = db.New().DeleteExpiredChallenges(ctx, markblogdb.Stdlib())
+	return res, err
+}
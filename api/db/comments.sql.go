@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"encore.dev/types/uuid"
@@ -23,7 +24,9 @@ RETURNING
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by
 `
 
 type CreateCommentParams struct {
@@ -42,10 +45,24 @@ func (q *Queries) CreateComment(ctx context.Context, db DBTX, arg CreateCommentP
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return &i, err
 }
 
+const deleteCommentsByUser = `-- name: DeleteCommentsByUser :exec
+DELETE FROM
+    comments
+WHERE
+    user_id = $1
+`
+
+func (q *Queries) DeleteCommentsByUser(ctx context.Context, db DBTX, userID *uuid.UUID) error {
+	_, err := db.ExecContext(ctx, deleteCommentsByUser, userID)
+	return err
+}
+
 const getCommentByID = `-- name: GetCommentByID :one
 SELECT
     id,
@@ -53,7 +70,9 @@ SELECT
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by
 FROM
     comments
 WHERE
@@ -70,34 +89,120 @@ func (q *Queries) GetCommentByID(ctx context.Context, db DBTX, id uuid.UUID) (*C
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return &i, err
 }
 
+const getDeletedCommentsByUser = `-- name: GetDeletedCommentsByUser :many
+SELECT
+    id,
+    post_id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by
+FROM
+    comments
+WHERE
+    user_id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+ORDER BY
+    deleted_at DESC
+`
+
+func (q *Queries) GetDeletedCommentsByUser(ctx context.Context, db DBTX, userID *uuid.UUID) ([]*Comment, error) {
+	rows, err := db.QueryContext(ctx, getDeletedCommentsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Comment{}
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLatestCommentsForPost = `-- name: GetLatestCommentsForPost :many
 SELECT
     c.id,
     c.content,
     c.created_at,
-    u.username AS username
+    COALESCE(u.username, '') AS username,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = $1) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = c.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     comments c
 JOIN
+    posts p ON c.post_id = p.id
+LEFT JOIN
     users u ON c.user_id = u.id
 WHERE
-    c.post_id = $1
+    c.post_id = $2
+    AND c.deleted_at = TO_TIMESTAMP(0)
+    AND p.deleted_at = TO_TIMESTAMP(0)
 ORDER BY
     c.created_at DESC
 LIMIT
-    $2
-OFFSET
     $3
+OFFSET
+    $4
 `
 
 type GetLatestCommentsForPostParams struct {
-	PostID uuid.UUID
-	Limit  int32
-	Offset int32
+	ViewerID uuid.UUID
+	PostID   uuid.UUID
+	Limit    int32
+	Offset   int32
 }
 
 type GetLatestCommentsForPostRow struct {
@@ -105,10 +210,17 @@ type GetLatestCommentsForPostRow struct {
 	Content   string
 	CreatedAt time.Time
 	Username  string
+	Reactions json.RawMessage
 }
 
+// Reactions are counted per emoji, with whether the viewer used each.
 func (q *Queries) GetLatestCommentsForPost(ctx context.Context, db DBTX, arg GetLatestCommentsForPostParams) ([]*GetLatestCommentsForPostRow, error) {
-	rows, err := db.QueryContext(ctx, getLatestCommentsForPost, arg.PostID, arg.Limit, arg.Offset)
+	rows, err := db.QueryContext(ctx, getLatestCommentsForPost,
+		arg.ViewerID,
+		arg.PostID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +233,7 @@ func (q *Queries) GetLatestCommentsForPost(ctx context.Context, db DBTX, arg Get
 			&i.Content,
 			&i.CreatedAt,
 			&i.Username,
+			&i.Reactions,
 		); err != nil {
 			return nil, err
 		}
@@ -134,3 +247,62 @@ func (q *Queries) GetLatestCommentsForPost(ctx context.Context, db DBTX, arg Get
 	}
 	return items, nil
 }
+
+const purgeDeletedComments = `-- name: PurgeDeletedComments :execrows
+DELETE FROM
+    comments
+WHERE
+    deleted_at <> TO_TIMESTAMP(0)
+    AND deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedComments(ctx context.Context, db DBTX, deletedAt time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, purgeDeletedComments, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreComment = `-- name: RestoreComment :execrows
+UPDATE
+    comments
+SET
+    deleted_at = TO_TIMESTAMP(0),
+    deleted_by = NULL
+WHERE
+    id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+`
+
+func (q *Queries) RestoreComment(ctx context.Context, db DBTX, id uuid.UUID) (int64, error) {
+	result, err := db.ExecContext(ctx, restoreComment, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const softDeleteComment = `-- name: SoftDeleteComment :execrows
+UPDATE
+    comments
+SET
+    deleted_at = NOW(),
+    deleted_by = $2
+WHERE
+    id = $1
+    AND deleted_at = TO_TIMESTAMP(0)
+`
+
+type SoftDeleteCommentParams struct {
+	ID        uuid.UUID
+	DeletedBy *uuid.UUID
+}
+
+func (q *Queries) SoftDeleteComment(ctx context.Context, db DBTX, arg SoftDeleteCommentParams) (int64, error) {
+	result, err := db.ExecContext(ctx, softDeleteComment, arg.ID, arg.DeletedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,56 @@
+--------------------------
+-- Site Settings Table
+--------------------------
+-- Instance-wide settings that admins can change at runtime. A missing key
+-- means the built-in default applies.
+CREATE TABLE
+    site_settings (
+        key VARCHAR(50) PRIMARY KEY,
+        value TEXT NOT NULL,
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+--------------------------
+-- Invites Table
+--------------------------
+-- An invite is spent once uses reaches max_uses. Revoking one simply ends
+-- it early, so that accounts created with it can still be traced to it.
+CREATE TABLE
+    invites (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        code VARCHAR(32) NOT NULL UNIQUE,
+        created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+        max_uses INT NOT NULL CHECK (max_uses > 0),
+        uses INT NOT NULL DEFAULT 0,
+        expires_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_invites_created_by ON invites (created_by);
+
+--------------------------
+-- Invite Redemptions Table
+--------------------------
+-- Who invited whom. invited_by is copied from the invite so that it stays
+-- readable at a glance.
+CREATE TABLE
+    invite_redemptions (
+        user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+        invite_id UUID NOT NULL REFERENCES invites (id) ON DELETE CASCADE,
+        invited_by UUID REFERENCES users (id) ON DELETE SET NULL,
+        redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_invite_redemptions_invited_by ON invite_redemptions (invited_by);
+
+--------------------------
+-- Invite Quotas Table
+--------------------------
+-- How many invites a user may create in total. Users without a row may
+-- not create any; admins are not limited.
+CREATE TABLE
+    invite_quotas (
+        user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+        quota INT NOT NULL CHECK (quota >= 0),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
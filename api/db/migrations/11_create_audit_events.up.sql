@@ -0,0 +1,40 @@
+--------------------------
+-- Audit Events Table
+--------------------------
+-- Security-relevant events such as logins. username is what the client
+-- sent, so that failed attempts on unknown accounts are recorded too.
+-- Rows are never changed; old ones are only pruned as a whole.
+CREATE TABLE
+    audit_events (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        event_type VARCHAR(50) NOT NULL,
+        user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+        username VARCHAR(50) NOT NULL DEFAULT '',
+        ip_address VARCHAR(45) NOT NULL,
+        user_agent TEXT NOT NULL,
+        outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('success', 'failure', 'error')),
+        detail TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id, created_at);
+
+-- The user_id foreign key still has to be able to null out rows of
+-- deleted accounts.
+CREATE
+OR REPLACE FUNCTION prevent_audit_event_update () RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.user_id IS NULL AND OLD.user_id IS NOT NULL
+        AND (NEW.id, NEW.event_type, NEW.username, NEW.ip_address, NEW.user_agent, NEW.outcome, NEW.detail, NEW.created_at)
+        = (OLD.id, OLD.event_type, OLD.username, OLD.ip_address, OLD.user_agent, OLD.outcome, OLD.detail, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audit_events_append_only BEFORE
+UPDATE ON audit_events FOR EACH ROW
+EXECUTE FUNCTION prevent_audit_event_update ();
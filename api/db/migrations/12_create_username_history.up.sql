@@ -0,0 +1,18 @@
+--------------------------
+-- Username History Table
+--------------------------
+-- One row per rename. An old username keeps resolving to the account that
+-- last gave it up, and is held back from other accounts for a while so
+-- that nobody can step into links pointing at a renamed user.
+CREATE TABLE
+    username_history (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        old_username VARCHAR(50) NOT NULL,
+        new_username VARCHAR(50) NOT NULL,
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_username_history_old_username ON username_history (old_username, changed_at);
+
+CREATE INDEX idx_username_history_user_id ON username_history (user_id, changed_at);
@@ -0,0 +1,17 @@
+--------------------------
+-- Spent Challenges Table
+--------------------------
+-- Proof-of-work challenges are signed rather than stored when issued. A
+-- solved one is recorded here until it expires so that it cannot be
+-- replayed; how many were spent recently also drives the difficulty.
+CREATE TABLE
+    spent_challenges (
+        nonce VARCHAR(64) PRIMARY KEY,
+        purpose VARCHAR(16) NOT NULL,
+        spent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        expires_at TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX idx_spent_challenges_spent_at ON spent_challenges (spent_at);
+
+CREATE INDEX idx_spent_challenges_expires_at ON spent_challenges (expires_at);
@@ -0,0 +1,15 @@
+--------------------------
+-- Post Revisions Table
+--------------------------
+-- Every version of a post that has since been edited away, numbered from 1
+-- for the original. The post itself holds the latest version, which is
+-- numbered one past its last revision. created_at is when the version was
+-- written, not when it was replaced.
+CREATE TABLE
+    post_revisions (
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        revision INT NOT NULL,
+        content TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (post_id, revision)
+    );
@@ -0,0 +1,31 @@
+--------------------------
+-- Soft Deletion
+--------------------------
+-- Deleted posts and comments stay in place until purged. deleted_at is
+-- TO_TIMESTAMP(0) for live ones, and deleted_by records whether the author
+-- or a moderator removed them.
+ALTER TABLE posts
+ADD COLUMN deleted_at TIMESTAMPTZ NOT NULL DEFAULT TO_TIMESTAMP(0),
+ADD COLUMN deleted_by UUID REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE comments
+ADD COLUMN deleted_at TIMESTAMPTZ NOT NULL DEFAULT TO_TIMESTAMP(0),
+ADD COLUMN deleted_by UUID REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_posts_deleted_at ON posts (deleted_at);
+
+CREATE INDEX idx_comments_deleted_at ON comments (deleted_at);
+
+-- updated_at tells readers a post or comment was edited, so deleting and
+-- restoring must not touch it.
+DROP TRIGGER trg_posts_updated_at ON posts;
+
+CREATE TRIGGER trg_posts_updated_at BEFORE
+UPDATE OF content ON posts FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column ();
+
+DROP TRIGGER trg_comments_updated_at ON comments;
+
+CREATE TRIGGER trg_comments_updated_at BEFORE
+UPDATE OF content ON comments FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column ();
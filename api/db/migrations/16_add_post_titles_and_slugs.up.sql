@@ -0,0 +1,32 @@
+--------------------------
+-- Post Titles and Slugs
+--------------------------
+-- Titles are optional. Slugs are unique per author and make up the
+-- permalink /@username/slug; posts written before slugs existed get their
+-- ID as one.
+ALTER TABLE posts
+ADD COLUMN title VARCHAR(200) NOT NULL DEFAULT '',
+ADD COLUMN slug VARCHAR(100) NOT NULL DEFAULT '';
+
+UPDATE posts
+SET
+    slug = id::text;
+
+CREATE UNIQUE INDEX idx_posts_user_id_slug ON posts (user_id, slug);
+
+--------------------------
+-- Post Slug History Table
+--------------------------
+-- Slugs a post has been renamed away from, so that old permalinks keep
+-- redirecting to it. A slug in here is not given to another post by the
+-- same author.
+CREATE TABLE
+    post_slug_history (
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        slug VARCHAR(100) NOT NULL,
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (user_id, slug)
+    );
+
+CREATE INDEX idx_post_slug_history_post_id ON post_slug_history (post_id);
@@ -0,0 +1,15 @@
+--------------------------
+-- Post Status
+--------------------------
+-- Drafts are only visible to their author. Scheduled posts become published
+-- once publish_at has passed. publish_at is when a post went, or is to go,
+-- live; posts written before drafts existed were published when created.
+ALTER TABLE posts
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published')),
+ADD COLUMN publish_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE posts
+SET
+    publish_at = created_at;
+
+CREATE INDEX idx_posts_status_publish_at ON posts (status, publish_at);
@@ -0,0 +1,26 @@
+--------------------------
+-- Tags Table
+--------------------------
+-- Hashtags are stored lowercase and without the leading #.
+CREATE TABLE
+    tags (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        name VARCHAR(50) NOT NULL UNIQUE,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+-- Lets prefix searches for autocomplete use an index.
+CREATE INDEX idx_tags_name_pattern ON tags (name text_pattern_ops);
+
+--------------------------
+-- Post Tags Table
+--------------------------
+-- The tags found in the current content of each post.
+CREATE TABLE
+    post_tags (
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        tag_id UUID NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+        PRIMARY KEY (post_id, tag_id)
+    );
+
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
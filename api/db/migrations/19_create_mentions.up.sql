@@ -0,0 +1,19 @@
+--------------------------
+-- Mentions Table
+--------------------------
+-- Users mentioned as @username in a post, or in a comment when comment_id
+-- is set. Usernames that matched no user are not recorded.
+CREATE TABLE
+    mentions (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        comment_id UUID REFERENCES comments (id) ON DELETE CASCADE,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_mentions_user_id_created_at ON mentions (user_id, created_at);
+
+CREATE INDEX idx_mentions_post_id ON mentions (post_id);
+
+CREATE INDEX idx_mentions_comment_id ON mentions (comment_id);
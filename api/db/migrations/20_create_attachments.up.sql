@@ -0,0 +1,37 @@
+--------------------------
+-- Attachments Table
+--------------------------
+-- Uploaded files. The object in the attachments bucket is named after the
+-- ID; images larger than a thumbnail also have an ID-thumb object. Images
+-- are stored re-encoded, without their metadata.
+CREATE TABLE
+    attachments (
+        id UUID PRIMARY KEY,
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        filename VARCHAR(255) NOT NULL,
+        content_type VARCHAR(100) NOT NULL,
+        size BIGINT NOT NULL,
+        width INT NOT NULL DEFAULT 0,
+        height INT NOT NULL DEFAULT 0,
+        has_thumbnail BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX idx_attachments_user_id ON attachments (user_id);
+
+CREATE INDEX idx_attachments_created_at ON attachments (created_at);
+
+--------------------------
+-- Post Attachments Table
+--------------------------
+-- The attachments embedded in the current content of each post, in the
+-- order they appear.
+CREATE TABLE
+    post_attachments (
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        attachment_id UUID NOT NULL REFERENCES attachments (id) ON DELETE CASCADE,
+        position INT NOT NULL,
+        PRIMARY KEY (post_id, attachment_id)
+    );
+
+CREATE INDEX idx_post_attachments_attachment_id ON post_attachments (attachment_id);
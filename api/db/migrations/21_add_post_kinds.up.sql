@@ -0,0 +1,16 @@
+--------------------------
+-- Post Kinds
+--------------------------
+-- Notes are short posts shown in full in the feed. Articles are long-form;
+-- feeds show their excerpt, a plain text opening written when the article
+-- is saved. word_count is counted over the same plain text.
+ALTER TABLE posts
+ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'note' CHECK (kind IN ('note', 'article')),
+ADD COLUMN excerpt TEXT NOT NULL DEFAULT '',
+ADD COLUMN word_count INT NOT NULL DEFAULT 0;
+
+UPDATE posts
+SET
+    word_count = COALESCE(ARRAY_LENGTH(REGEXP_SPLIT_TO_ARRAY(TRIM(content), '\s+'), 1), 0)
+WHERE
+    TRIM(content) <> '';
@@ -0,0 +1,22 @@
+--------------------------
+-- Reactions Table
+--------------------------
+-- Emoji reactions to a post, or to a comment when comment_id is set.
+-- target_id is the comment or post reacted to, so that each user reacts
+-- with an emoji at most once per target.
+CREATE TABLE
+    reactions (
+        post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+        comment_id UUID REFERENCES comments (id) ON DELETE CASCADE,
+        target_id UUID NOT NULL GENERATED ALWAYS AS (COALESCE(comment_id, post_id)) STORED,
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        emoji VARCHAR(32) NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (target_id, user_id, emoji)
+    );
+
+CREATE INDEX idx_reactions_post_id ON reactions (post_id);
+
+CREATE INDEX idx_reactions_comment_id ON reactions (comment_id);
+
+CREATE INDEX idx_reactions_user_id ON reactions (user_id);
@@ -0,0 +1,10 @@
+--------------------------
+-- Attachments Table
+--------------------------
+-- Attachments outlive the account that uploaded them, with user_id set to
+-- NULL, so that the orphaned attachments job still finds them and removes
+-- the stored files.
+ALTER TABLE attachments
+ALTER COLUMN user_id DROP NOT NULL,
+DROP CONSTRAINT attachments_user_id_fkey,
+ADD CONSTRAINT attachments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,12 @@
+--------------------------
+-- Account Deletions Table
+--------------------------
+CREATE TABLE
+    account_deletions (
+        user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+        keep_comments BOOLEAN NOT NULL DEFAULT TRUE,
+        requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        scheduled_for TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX idx_account_deletions_scheduled_for ON account_deletions (scheduled_for);
@@ -0,0 +1,34 @@
+--------------------------
+-- User TOTP Table
+--------------------------
+CREATE TABLE
+    user_totp (
+        user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+        secret VARCHAR(64) NOT NULL,
+        enabled BOOLEAN NOT NULL DEFAULT FALSE,
+        last_used_step BIGINT NOT NULL DEFAULT 0,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+--------------------------
+-- Recovery Codes Table
+--------------------------
+CREATE TABLE
+    recovery_codes (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        code_hash VARCHAR(64) NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        UNIQUE (user_id, code_hash)
+    );
+
+--------------------------
+-- Other things
+--------------------------
+ALTER TABLE sessions
+ADD COLUMN two_factor_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TRIGGER trg_user_totp_updated_at BEFORE
+UPDATE ON user_totp FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column ();
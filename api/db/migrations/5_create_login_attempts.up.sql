@@ -0,0 +1,14 @@
+--------------------------
+-- Login Attempts Table
+--------------------------
+CREATE TABLE
+    login_attempts (
+        kind VARCHAR(16) NOT NULL,
+        key TEXT NOT NULL,
+        failures INTEGER NOT NULL DEFAULT 0,
+        last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        locked_until TIMESTAMPTZ NOT NULL DEFAULT TO_TIMESTAMP(0),
+        PRIMARY KEY (kind, key)
+    );
+
+CREATE INDEX idx_login_attempts_locked_until ON login_attempts (locked_until);
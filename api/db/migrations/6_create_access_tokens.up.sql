@@ -0,0 +1,16 @@
+--------------------------
+-- Access Tokens Table
+--------------------------
+CREATE TABLE
+    access_tokens (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        name VARCHAR(100) NOT NULL,
+        token_prefix VARCHAR(16) NOT NULL,
+        token_hash VARCHAR(64) NOT NULL UNIQUE,
+        scopes TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        last_used_at TIMESTAMPTZ NOT NULL DEFAULT TO_TIMESTAMP(0)
+    );
+
+CREATE INDEX idx_access_tokens_user_id ON access_tokens (user_id);
@@ -0,0 +1,13 @@
+--------------------------
+-- User Roles Table
+--------------------------
+-- Every account is implicitly a plain user; only elevated roles are stored.
+CREATE TABLE
+    user_roles (
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        role VARCHAR(20) NOT NULL CHECK (role IN ('moderator', 'admin')),
+        granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (user_id, role)
+    );
+
+CREATE INDEX idx_user_roles_role ON user_roles (role);
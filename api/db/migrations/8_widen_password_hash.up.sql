@@ -0,0 +1,7 @@
+--------------------------
+-- Users Table
+--------------------------
+-- Encoded argon2id hashes carry their parameters and salt, and must leave
+-- room for stronger settings later on.
+ALTER TABLE users
+ALTER COLUMN password_hash TYPE VARCHAR(255);
@@ -0,0 +1,29 @@
+--------------------------
+-- Users Table
+--------------------------
+-- An empty email means none was given. Only verified addresses have to be
+-- unique, so nobody can block an address by claiming it first.
+ALTER TABLE users
+ADD COLUMN email VARCHAR(254) NOT NULL DEFAULT '',
+ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX idx_users_verified_email ON users (LOWER(email))
+WHERE
+    email_verified;
+
+--------------------------
+-- Email Tokens Table
+--------------------------
+-- Single-use tokens sent by mail, stored hashed. The address a token was
+-- sent to is kept so that a later email change invalidates it.
+CREATE TABLE
+    email_tokens (
+        token_hash VARCHAR(64) PRIMARY KEY,
+        user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+        email VARCHAR(254) NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        expires_at TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX idx_email_tokens_user_id ON email_tokens (user_id);
@@ -10,6 +10,48 @@ import (
 	"encore.dev/types/uuid"
 )
 
+type AccessToken struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	TokenPrefix string
+	TokenHash   string
+	Scopes      string
+	CreatedAt   time.Time
+	LastUsedAt  time.Time
+}
+
+type AccountDeletion struct {
+	UserID       uuid.UUID
+	KeepComments bool
+	RequestedAt  time.Time
+	ScheduledFor time.Time
+}
+
+type Attachment struct {
+	ID           uuid.UUID
+	UserID       *uuid.UUID
+	Filename     string
+	ContentType  string
+	Size         int64
+	Width        int32
+	Height       int32
+	HasThumbnail bool
+	CreatedAt    time.Time
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	EventType string
+	UserID    *uuid.UUID
+	Username  string
+	IpAddress string
+	UserAgent string
+	Outcome   string
+	Detail    string
+	CreatedAt time.Time
+}
+
 type Comment struct {
 	ID        uuid.UUID
 	PostID    uuid.UUID
@@ -17,6 +59,56 @@ type Comment struct {
 	Content   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt time.Time
+	DeletedBy *uuid.UUID
+}
+
+type EmailToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Invite struct {
+	ID        uuid.UUID
+	Code      string
+	CreatedBy *uuid.UUID
+	MaxUses   int32
+	Uses      int32
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type InviteQuota struct {
+	UserID    uuid.UUID
+	Quota     int32
+	UpdatedAt time.Time
+}
+
+type InviteRedemption struct {
+	UserID     uuid.UUID
+	InviteID   uuid.UUID
+	InvitedBy  *uuid.UUID
+	RedeemedAt time.Time
+}
+
+type LoginAttempt struct {
+	Kind          string
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+type Mention struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CommentID *uuid.UUID
+	CreatedAt time.Time
 }
 
 type Post struct {
@@ -25,12 +117,117 @@ type Post struct {
 	Content   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt time.Time
+	DeletedBy *uuid.UUID
+	Title     string
+	Slug      string
+	Status    string
+	PublishAt time.Time
+	Kind      string
+	Excerpt   string
+	WordCount int32
+}
+
+type PostAttachment struct {
+	PostID       uuid.UUID
+	AttachmentID uuid.UUID
+	Position     int32
+}
+
+type PostRevision struct {
+	PostID    uuid.UUID
+	Revision  int32
+	Content   string
+	CreatedAt time.Time
+}
+
+type PostSlugHistory struct {
+	UserID    uuid.UUID
+	Slug      string
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PostTag struct {
+	PostID uuid.UUID
+	TagID  uuid.UUID
+}
+
+type Reaction struct {
+	PostID    uuid.UUID
+	CommentID *uuid.UUID
+	TargetID  uuid.UUID
+	UserID    uuid.UUID
+	Emoji     string
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+}
+
+type Session struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	UserAgent        string
+	IpAddress        string
+	CreatedAt        time.Time
+	LastSeenAt       time.Time
+	ExpiresAt        time.Time
+	TwoFactorPending bool
+}
+
+type SiteSetting struct {
+	Key       string
+	Value     string
+	UpdatedAt time.Time
+}
+
+type SpentChallenge struct {
+	Nonce     string
+	Purpose   string
+	SpentAt   time.Time
+	ExpiresAt time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
 }
 
 type User struct {
-	ID           uuid.UUID
-	Username     string
-	PasswordHash string
+	ID            uuid.UUID
+	Username      string
+	PasswordHash  string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Email         string
+	EmailVerified bool
+}
+
+type UserRole struct {
+	UserID    uuid.UUID
+	Role      string
+	GrantedAt time.Time
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+type UsernameHistory struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	OldUsername string
+	NewUsername string
+	ChangedAt   time.Time
+}
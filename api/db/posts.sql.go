@@ -7,31 +7,94 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"encore.dev/types/uuid"
 )
 
+const countPostsByUser = `-- name: CountPostsByUser :one
+SELECT
+    COUNT(*)
+FROM
+    posts
+WHERE
+    user_id = $1
+`
+
+func (q *Queries) CountPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) (int64, error) {
+	row := db.QueryRowContext(ctx, countPostsByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO
-    posts (user_id, content)
+    posts (
+        user_id,
+        content,
+        title,
+        slug,
+        status,
+        publish_at,
+        kind,
+        excerpt,
+        word_count
+    )
 VALUES
-    ($1, $2)
+    (
+        $1,
+        $2,
+        $3,
+        $4,
+        $5,
+        GREATEST($6::TIMESTAMPTZ, NOW()),
+        $7,
+        $8,
+        $9
+    )
 RETURNING
     id,
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
 `
 
 type CreatePostParams struct {
-	UserID  uuid.UUID
-	Content string
+	UserID    uuid.UUID
+	Content   string
+	Title     string
+	Slug      string
+	Status    string
+	PublishAt time.Time
+	Kind      string
+	Excerpt   string
+	WordCount int32
 }
 
 func (q *Queries) CreatePost(ctx context.Context, db DBTX, arg CreatePostParams) (*Post, error) {
-	row := db.QueryRowContext(ctx, createPost, arg.UserID, arg.Content)
+	row := db.QueryRowContext(ctx, createPost,
+		arg.UserID,
+		arg.Content,
+		arg.Title,
+		arg.Slug,
+		arg.Status,
+		arg.PublishAt,
+		arg.Kind,
+		arg.Excerpt,
+		arg.WordCount,
+	)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -39,42 +102,191 @@ func (q *Queries) CreatePost(ctx context.Context, db DBTX, arg CreatePostParams)
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
 	)
 	return &i, err
 }
 
+const getDeletedPostsByUser = `-- name: GetDeletedPostsByUser :many
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+ORDER BY
+    deleted_at DESC
+`
+
+func (q *Queries) GetDeletedPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*Post, error) {
+	rows, err := db.QueryContext(ctx, getDeletedPostsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Title,
+			&i.Slug,
+			&i.Status,
+			&i.PublishAt,
+			&i.Kind,
+			&i.Excerpt,
+			&i.WordCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLatestPosts = `-- name: GetLatestPosts :many
 SELECT 
     p.id,
-    p.content,
+    (CASE WHEN p.kind = 'article' THEN p.excerpt ELSE p.content END)::TEXT AS content,
     p.created_at,
-    u.username
+    u.username,
+    p.updated_at > p.publish_at AS edited,
+    p.title,
+    p.slug,
+    p.status,
+    p.publish_at,
+    p.kind,
+    p.word_count,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'id', a.id,
+                        'filename', a.filename,
+                        'content_type', a.content_type,
+                        'size', a.size,
+                        'width', a.width,
+                        'height', a.height,
+                        'has_thumbnail', a.has_thumbnail
+                    )
+                    ORDER BY pa.position
+                )
+            FROM
+                post_attachments pa
+                JOIN attachments a ON pa.attachment_id = a.id
+            WHERE
+                pa.post_id = p.id
+        ),
+        '[]'
+    )::JSON AS attachments,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = $1) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = p.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM 
     posts p
 JOIN 
     users u ON p.user_id = u.id
+WHERE
+    p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = $1
+    )
 ORDER BY 
-    p.created_at DESC
+    p.publish_at DESC
 LIMIT 
-    $1
-OFFSET 
     $2
+OFFSET 
+    $3
 `
 
 type GetLatestPostsParams struct {
-	Limit  int32
-	Offset int32
+	ViewerID uuid.UUID
+	Limit    int32
+	Offset   int32
 }
 
 type GetLatestPostsRow struct {
-	ID        uuid.UUID
-	Content   string
-	CreatedAt time.Time
-	Username  string
+	ID          uuid.UUID
+	Content     string
+	CreatedAt   time.Time
+	Username    string
+	Edited      bool
+	Title       string
+	Slug        string
+	Status      string
+	PublishAt   time.Time
+	Kind        string
+	WordCount   int32
+	Attachments json.RawMessage
+	Reactions   json.RawMessage
 }
 
+// Published posts, newest first. The viewer also sees their own drafts and
+// scheduled posts. Articles come with their excerpt in place of the content.
+// Reactions are counted per emoji, with whether the viewer used each.
 func (q *Queries) GetLatestPosts(ctx context.Context, db DBTX, arg GetLatestPostsParams) ([]*GetLatestPostsRow, error) {
-	rows, err := db.QueryContext(ctx, getLatestPosts, arg.Limit, arg.Offset)
+	rows, err := db.QueryContext(ctx, getLatestPosts, arg.ViewerID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +299,15 @@ func (q *Queries) GetLatestPosts(ctx context.Context, db DBTX, arg GetLatestPost
 			&i.Content,
 			&i.CreatedAt,
 			&i.Username,
+			&i.Edited,
+			&i.Title,
+			&i.Slug,
+			&i.Status,
+			&i.PublishAt,
+			&i.Kind,
+			&i.WordCount,
+			&i.Attachments,
+			&i.Reactions,
 		); err != nil {
 			return nil, err
 		}
@@ -107,7 +328,16 @@ SELECT
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
 FROM
     posts
 WHERE
@@ -123,6 +353,436 @@ func (q *Queries) GetPostByID(ctx context.Context, db DBTX, id uuid.UUID) (*Post
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
+	)
+	return &i, err
+}
+
+const getPostByIDForUpdate = `-- name: GetPostByIDForUpdate :one
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetPostByIDForUpdate(ctx context.Context, db DBTX, id uuid.UUID) (*Post, error) {
+	row := db.QueryRowContext(ctx, getPostByIDForUpdate, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
+	)
+	return &i, err
+}
+
+const getPostBySlug = `-- name: GetPostBySlug :one
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND slug = $2
+`
+
+type GetPostBySlugParams struct {
+	UserID uuid.UUID
+	Slug   string
+}
+
+func (q *Queries) GetPostBySlug(ctx context.Context, db DBTX, arg GetPostBySlugParams) (*Post, error) {
+	row := db.QueryRowContext(ctx, getPostBySlug, arg.UserID, arg.Slug)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
+	)
+	return &i, err
+}
+
+const getUnpublishedPostsByUser = `-- name: GetUnpublishedPostsByUser :many
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND status <> 'published'
+    AND deleted_at = TO_TIMESTAMP(0)
+ORDER BY
+    updated_at DESC
+`
+
+func (q *Queries) GetUnpublishedPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*Post, error) {
+	rows, err := db.QueryContext(ctx, getUnpublishedPostsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Title,
+			&i.Slug,
+			&i.Status,
+			&i.PublishAt,
+			&i.Kind,
+			&i.Excerpt,
+			&i.WordCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDuePosts = `-- name: PublishDuePosts :execrows
+UPDATE
+    posts
+SET
+    status = 'published'
+WHERE
+    status = 'scheduled'
+    AND publish_at <= NOW()
+    AND deleted_at = TO_TIMESTAMP(0)
+`
+
+func (q *Queries) PublishDuePosts(ctx context.Context, db DBTX) (int64, error) {
+	result, err := db.ExecContext(ctx, publishDuePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeDeletedPosts = `-- name: PurgeDeletedPosts :execrows
+DELETE FROM
+    posts
+WHERE
+    deleted_at <> TO_TIMESTAMP(0)
+    AND deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedPosts(ctx context.Context, db DBTX, deletedAt time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, purgeDeletedPosts, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePost = `-- name: RestorePost :execrows
+UPDATE
+    posts
+SET
+    deleted_at = TO_TIMESTAMP(0),
+    deleted_by = NULL
+WHERE
+    id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+`
+
+func (q *Queries) RestorePost(ctx context.Context, db DBTX, id uuid.UUID) (int64, error) {
+	result, err := db.ExecContext(ctx, restorePost, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const softDeletePost = `-- name: SoftDeletePost :execrows
+UPDATE
+    posts
+SET
+    deleted_at = NOW(),
+    deleted_by = $2
+WHERE
+    id = $1
+    AND deleted_at = TO_TIMESTAMP(0)
+`
+
+type SoftDeletePostParams struct {
+	ID        uuid.UUID
+	DeletedBy *uuid.UUID
+}
+
+func (q *Queries) SoftDeletePost(ctx context.Context, db DBTX, arg SoftDeletePostParams) (int64, error) {
+	result, err := db.ExecContext(ctx, softDeletePost, arg.ID, arg.DeletedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE
+    posts
+SET
+    content = $1,
+    excerpt = $2,
+    word_count = $3,
+    title = $4,
+    status = $5,
+    publish_at = GREATEST($6::TIMESTAMPTZ, NOW())
+WHERE
+    id = $7
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+`
+
+type UpdateDraftParams struct {
+	Content   string
+	Excerpt   string
+	WordCount int32
+	Title     string
+	Status    string
+	PublishAt time.Time
+	ID        uuid.UUID
+}
+
+// publish_at is never set in the past, so that a post published now does
+// not count as edited since.
+func (q *Queries) UpdateDraft(ctx context.Context, db DBTX, arg UpdateDraftParams) (*Post, error) {
+	row := db.QueryRowContext(ctx, updateDraft,
+		arg.Content,
+		arg.Excerpt,
+		arg.WordCount,
+		arg.Title,
+		arg.Status,
+		arg.PublishAt,
+		arg.ID,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
+	)
+	return &i, err
+}
+
+const updatePostContent = `-- name: UpdatePostContent :one
+UPDATE
+    posts
+SET
+    content = $2,
+    excerpt = $3,
+    word_count = $4
+WHERE
+    id = $1
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+`
+
+type UpdatePostContentParams struct {
+	ID        uuid.UUID
+	Content   string
+	Excerpt   string
+	WordCount int32
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, db DBTX, arg UpdatePostContentParams) (*Post, error) {
+	row := db.QueryRowContext(ctx, updatePostContent,
+		arg.ID,
+		arg.Content,
+		arg.Excerpt,
+		arg.WordCount,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
+	)
+	return &i, err
+}
+
+const updatePostPermalink = `-- name: UpdatePostPermalink :one
+UPDATE
+    posts
+SET
+    title = $2,
+    slug = $3
+WHERE
+    id = $1
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+`
+
+type UpdatePostPermalinkParams struct {
+	ID    uuid.UUID
+	Title string
+	Slug  string
+}
+
+func (q *Queries) UpdatePostPermalink(ctx context.Context, db DBTX, arg UpdatePostPermalinkParams) (*Post, error) {
+	row := db.QueryRowContext(ctx, updatePostPermalink, arg.ID, arg.Title, arg.Slug)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Title,
+		&i.Slug,
+		&i.Status,
+		&i.PublishAt,
+		&i.Kind,
+		&i.Excerpt,
+		&i.WordCount,
 	)
 	return &i, err
 }
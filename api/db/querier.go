@@ -6,22 +6,167 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"encore.dev/types/uuid"
 )
 
 type Querier interface {
+	CancelAccountDeletion(ctx context.Context, db DBTX, userID uuid.UUID) (int64, error)
 	CheckUserExists(ctx context.Context, db DBTX, username string) (bool, error)
+	ClearLoginAttempts(ctx context.Context, db DBTX, arg ClearLoginAttemptsParams) (int64, error)
+	ConsumeEmailToken(ctx context.Context, db DBTX, arg ConsumeEmailTokenParams) (*EmailToken, error)
+	ConsumeRecoveryCode(ctx context.Context, db DBTX, arg ConsumeRecoveryCodeParams) (int64, error)
+	CountChallengesSpentSince(ctx context.Context, db DBTX, arg CountChallengesSpentSinceParams) (int64, error)
+	CountInviteUsesCreatedBy(ctx context.Context, db DBTX, createdBy *uuid.UUID) (int64, error)
+	CountPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) (int64, error)
+	CountRoleHolders(ctx context.Context, db DBTX, role string) (int64, error)
+	CreateAccessToken(ctx context.Context, db DBTX, arg CreateAccessTokenParams) (*AccessToken, error)
+	CreateAttachment(ctx context.Context, db DBTX, arg CreateAttachmentParams) (*Attachment, error)
+	CreateAuditEvent(ctx context.Context, db DBTX, arg CreateAuditEventParams) error
 	CreateComment(ctx context.Context, db DBTX, arg CreateCommentParams) (*Comment, error)
+	CreateEmailToken(ctx context.Context, db DBTX, arg CreateEmailTokenParams) (*EmailToken, error)
+	CreateInvite(ctx context.Context, db DBTX, arg CreateInviteParams) (*Invite, error)
+	CreateInviteRedemption(ctx context.Context, db DBTX, arg CreateInviteRedemptionParams) error
+	CreateMention(ctx context.Context, db DBTX, arg CreateMentionParams) error
 	CreatePost(ctx context.Context, db DBTX, arg CreatePostParams) (*Post, error)
+	CreatePostAttachment(ctx context.Context, db DBTX, arg CreatePostAttachmentParams) error
+	CreatePostRevision(ctx context.Context, db DBTX, arg CreatePostRevisionParams) error
+	CreatePostSlugRedirect(ctx context.Context, db DBTX, arg CreatePostSlugRedirectParams) error
+	CreatePostTag(ctx context.Context, db DBTX, arg CreatePostTagParams) error
+	CreateReaction(ctx context.Context, db DBTX, arg CreateReactionParams) (int64, error)
+	CreateRecoveryCode(ctx context.Context, db DBTX, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, db DBTX, arg CreateSessionParams) (*Session, error)
 	CreateUser(ctx context.Context, db DBTX, arg CreateUserParams) (*User, error)
+	CreateUsernameChange(ctx context.Context, db DBTX, arg CreateUsernameChangeParams) error
+	DeleteAccessTokenForUser(ctx context.Context, db DBTX, arg DeleteAccessTokenForUserParams) (int64, error)
+	DeleteAttachment(ctx context.Context, db DBTX, id uuid.UUID) error
+	DeleteAuditEventsBefore(ctx context.Context, db DBTX, createdAt time.Time) (int64, error)
+	DeleteCommentsByUser(ctx context.Context, db DBTX, userID *uuid.UUID) error
+	DeleteEmailTokensForUser(ctx context.Context, db DBTX, arg DeleteEmailTokensForUserParams) error
+	DeleteExpiredChallenges(ctx context.Context, db DBTX) (int64, error)
+	DeleteExpiredEmailTokens(ctx context.Context, db DBTX) (int64, error)
+	DeleteExpiredSessions(ctx context.Context, db DBTX) (int64, error)
+	// Removes the mentions made by a post itself, or by one of its comments
+	// when comment_id is set.
+	DeleteMentionsForSource(ctx context.Context, db DBTX, arg DeleteMentionsForSourceParams) error
+	DeleteOtherSessionsForUser(ctx context.Context, db DBTX, arg DeleteOtherSessionsForUserParams) (int64, error)
+	DeletePostAttachments(ctx context.Context, db DBTX, postID uuid.UUID) error
+	DeletePostSlugRedirect(ctx context.Context, db DBTX, arg DeletePostSlugRedirectParams) error
+	DeletePostTags(ctx context.Context, db DBTX, postID uuid.UUID) error
+	// Takes back a reaction. Emoji are compared without variation selectors,
+	// so that a reaction stored as ❤ is found when ❤️ is toggled.
+	DeleteReaction(ctx context.Context, db DBTX, arg DeleteReactionParams) (int64, error)
+	DeleteRecoveryCodesForUser(ctx context.Context, db DBTX, userID uuid.UUID) error
+	DeleteSession(ctx context.Context, db DBTX, id uuid.UUID) error
+	DeleteSessionForUser(ctx context.Context, db DBTX, arg DeleteSessionForUserParams) (int64, error)
+	DeleteSessionsForUser(ctx context.Context, db DBTX, userID uuid.UUID) (int64, error)
+	DeleteStaleLoginAttempts(ctx context.Context, db DBTX) (int64, error)
+	DeleteUser(ctx context.Context, db DBTX, id uuid.UUID) error
+	DeleteUserTOTP(ctx context.Context, db DBTX, userID uuid.UUID) error
+	EnableUserTOTP(ctx context.Context, db DBTX, arg EnableUserTOTPParams) error
+	GetAccessTokenByHash(ctx context.Context, db DBTX, tokenHash string) (*AccessToken, error)
+	GetAccessTokensForUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*AccessToken, error)
+	GetAccountDeletionForUser(ctx context.Context, db DBTX, userID uuid.UUID) (*AccountDeletion, error)
+	GetActiveLoginLocks(ctx context.Context, db DBTX, arg GetActiveLoginLocksParams) ([]*LoginAttempt, error)
+	GetActiveSessionByID(ctx context.Context, db DBTX, id uuid.UUID) (*Session, error)
+	GetActiveSessionsForUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*Session, error)
+	GetAttachmentByID(ctx context.Context, db DBTX, id uuid.UUID) (*Attachment, error)
+	// Empty filters match everything.
+	GetAuditEvents(ctx context.Context, db DBTX, arg GetAuditEventsParams) ([]*AuditEvent, error)
 	GetCommentByID(ctx context.Context, db DBTX, id uuid.UUID) (*Comment, error)
+	GetDeletedCommentsByUser(ctx context.Context, db DBTX, userID *uuid.UUID) ([]*Comment, error)
+	GetDeletedPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*Post, error)
+	GetDueAccountDeletions(ctx context.Context, db DBTX, limit int32) ([]*AccountDeletion, error)
+	GetInviteQuota(ctx context.Context, db DBTX, userID uuid.UUID) (int32, error)
+	GetInviteQuotaForUpdate(ctx context.Context, db DBTX, userID uuid.UUID) (int32, error)
+	GetInviteRedemptions(ctx context.Context, db DBTX, arg GetInviteRedemptionsParams) ([]*GetInviteRedemptionsRow, error)
+	GetInvitesCreatedBy(ctx context.Context, db DBTX, createdBy *uuid.UUID) ([]*Invite, error)
+	GetLastUsernameChange(ctx context.Context, db DBTX, userID uuid.UUID) (time.Time, error)
+	// Reactions are counted per emoji, with whether the viewer used each.
 	GetLatestCommentsForPost(ctx context.Context, db DBTX, arg GetLatestCommentsForPostParams) ([]*GetLatestCommentsForPostRow, error)
+	// Mentions of a user in published posts and their comments, newest first.
+	GetLatestMentionsForUser(ctx context.Context, db DBTX, arg GetLatestMentionsForUserParams) ([]*GetLatestMentionsForUserRow, error)
+	// Published posts, newest first. The viewer also sees their own drafts and
+	// scheduled posts. Articles come with their excerpt in place of the content.
+	// Reactions are counted per emoji, with whether the viewer used each.
 	GetLatestPosts(ctx context.Context, db DBTX, arg GetLatestPostsParams) ([]*GetLatestPostsRow, error)
+	// Like GetLatestPosts, limited to posts carrying the tag.
+	GetLatestPostsByTag(ctx context.Context, db DBTX, arg GetLatestPostsByTagParams) ([]*GetLatestPostsByTagRow, error)
+	// A user's posts and comments, newest first. Only the author of a draft or
+	// scheduled post sees it and the comments on it. Articles come with their
+	// excerpt in place of the content. Reactions are counted per emoji, with
+	// whether the viewer used each.
 	GetLatestUserActivity(ctx context.Context, db DBTX, arg GetLatestUserActivityParams) ([]*GetLatestUserActivityRow, error)
+	GetLockedLoginAttempts(ctx context.Context, db DBTX, arg GetLockedLoginAttemptsParams) ([]*LoginAttempt, error)
+	// Attachments created before the given time that no post embeds, neither
+	// now nor in an earlier revision. Only the uploader can embed an
+	// attachment, so only their revisions are searched. Attachments of deleted
+	// accounts go regardless of age.
+	GetOrphanedAttachments(ctx context.Context, db DBTX, arg GetOrphanedAttachmentsParams) ([]*Attachment, error)
+	GetPostAttachments(ctx context.Context, db DBTX, postID uuid.UUID) ([]*Attachment, error)
 	GetPostByID(ctx context.Context, db DBTX, id uuid.UUID) (*Post, error)
+	GetPostByIDForUpdate(ctx context.Context, db DBTX, id uuid.UUID) (*Post, error)
+	GetPostBySlug(ctx context.Context, db DBTX, arg GetPostBySlugParams) (*Post, error)
+	GetPostIDBySlugHistory(ctx context.Context, db DBTX, arg GetPostIDBySlugHistoryParams) (uuid.UUID, error)
+	GetPostRevisions(ctx context.Context, db DBTX, postID uuid.UUID) ([]*PostRevision, error)
+	GetPostTags(ctx context.Context, db DBTX, postID uuid.UUID) ([]string, error)
+	// The account that most recently gave up the username after since.
+	GetPreviousUsernameOwner(ctx context.Context, db DBTX, arg GetPreviousUsernameOwnerParams) (uuid.UUID, error)
+	// How many users reacted to a post or comment with each emoji, most used
+	// first, and whether the viewer is one of them.
+	GetReactionCounts(ctx context.Context, db DBTX, arg GetReactionCountsParams) ([]*GetReactionCountsRow, error)
+	// Who reacted to a post or comment and with what, oldest first.
+	GetReactionsForTarget(ctx context.Context, db DBTX, arg GetReactionsForTargetParams) ([]*GetReactionsForTargetRow, error)
+	GetRecentAuditEventsForUser(ctx context.Context, db DBTX, arg GetRecentAuditEventsForUserParams) ([]*AuditEvent, error)
+	GetRoleAssignments(ctx context.Context, db DBTX) ([]*GetRoleAssignmentsRow, error)
+	GetSiteSetting(ctx context.Context, db DBTX, key string) (string, error)
+	// Tags by how many published posts used them since the given time.
+	GetTrendingTags(ctx context.Context, db DBTX, arg GetTrendingTagsParams) ([]*GetTrendingTagsRow, error)
+	GetUnpublishedPostsByUser(ctx context.Context, db DBTX, userID uuid.UUID) ([]*Post, error)
 	GetUserByID(ctx context.Context, db DBTX, id uuid.UUID) (*User, error)
 	GetUserByUsername(ctx context.Context, db DBTX, username string) (*User, error)
+	GetUserByVerifiedEmail(ctx context.Context, db DBTX, lower string) (*User, error)
+	GetUserRoles(ctx context.Context, db DBTX, userID uuid.UUID) ([]string, error)
+	GetUserTOTP(ctx context.Context, db DBTX, userID uuid.UUID) (*UserTotp, error)
+	GetUsernameHistory(ctx context.Context, db DBTX, userID uuid.UUID) ([]*UsernameHistory, error)
+	GrantUserRole(ctx context.Context, db DBTX, arg GrantUserRoleParams) (int64, error)
+	// Whether the author already uses slug for a post other than post_id,
+	// either currently or as a redirect.
+	IsPostSlugTaken(ctx context.Context, db DBTX, arg IsPostSlugTakenParams) (bool, error)
+	MarkUserEmailVerified(ctx context.Context, db DBTX, arg MarkUserEmailVerifiedParams) (int64, error)
+	PublishDuePosts(ctx context.Context, db DBTX) (int64, error)
+	PurgeDeletedComments(ctx context.Context, db DBTX, deletedAt time.Time) (int64, error)
+	PurgeDeletedPosts(ctx context.Context, db DBTX, deletedAt time.Time) (int64, error)
+	RecordLoginFailure(ctx context.Context, db DBTX, arg RecordLoginFailureParams) (*LoginAttempt, error)
+	RedeemInvite(ctx context.Context, db DBTX, code string) (*Invite, error)
+	RestoreComment(ctx context.Context, db DBTX, id uuid.UUID) (int64, error)
+	RestorePost(ctx context.Context, db DBTX, id uuid.UUID) (int64, error)
+	RevokeInvite(ctx context.Context, db DBTX, arg RevokeInviteParams) (int64, error)
+	RevokeUserRole(ctx context.Context, db DBTX, arg RevokeUserRoleParams) (int64, error)
+	ScheduleAccountDeletion(ctx context.Context, db DBTX, arg ScheduleAccountDeletionParams) (*AccountDeletion, error)
+	// Tags whose name matches a LIKE pattern, most used first. Only published
+	// posts count, so tags used only in drafts are not suggested.
+	SearchTagsByPrefix(ctx context.Context, db DBTX, arg SearchTagsByPrefixParams) ([]*SearchTagsByPrefixRow, error)
+	SetInviteQuota(ctx context.Context, db DBTX, arg SetInviteQuotaParams) error
+	SetLoginLockedUntil(ctx context.Context, db DBTX, arg SetLoginLockedUntilParams) error
+	SetUserEmail(ctx context.Context, db DBTX, arg SetUserEmailParams) error
+	SoftDeleteComment(ctx context.Context, db DBTX, arg SoftDeleteCommentParams) (int64, error)
+	SoftDeletePost(ctx context.Context, db DBTX, arg SoftDeletePostParams) (int64, error)
+	SpendChallenge(ctx context.Context, db DBTX, arg SpendChallengeParams) (int64, error)
+	TouchAccessToken(ctx context.Context, db DBTX, id uuid.UUID) error
+	TouchSession(ctx context.Context, db DBTX, id uuid.UUID) error
+	// publish_at is never set in the past, so that a post published now does
+	// not count as edited since.
+	UpdateDraft(ctx context.Context, db DBTX, arg UpdateDraftParams) (*Post, error)
+	UpdatePostContent(ctx context.Context, db DBTX, arg UpdatePostContentParams) (*Post, error)
+	UpdatePostPermalink(ctx context.Context, db DBTX, arg UpdatePostPermalinkParams) (*Post, error)
+	UpdateUserPassword(ctx context.Context, db DBTX, arg UpdateUserPasswordParams) error
+	UpdateUsername(ctx context.Context, db DBTX, arg UpdateUsernameParams) error
+	UpsertSiteSetting(ctx context.Context, db DBTX, arg UpsertSiteSettingParams) error
+	UpsertTag(ctx context.Context, db DBTX, name string) (uuid.UUID, error)
+	UpsertUserTOTP(ctx context.Context, db DBTX, arg UpsertUserTOTPParams) (*UserTotp, error)
+	UseUserTOTPStep(ctx context.Context, db DBTX, arg UseUserTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,61 @@
+-- name: CreateAccessToken :one
+INSERT INTO
+    access_tokens (user_id, name, token_prefix, token_hash, scopes)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    id,
+    user_id,
+    name,
+    token_prefix,
+    token_hash,
+    scopes,
+    created_at,
+    last_used_at;
+
+-- name: GetAccessTokenByHash :one
+SELECT
+    id,
+    user_id,
+    name,
+    token_prefix,
+    token_hash,
+    scopes,
+    created_at,
+    last_used_at
+FROM
+    access_tokens
+WHERE
+    token_hash = $1;
+
+-- name: GetAccessTokensForUser :many
+SELECT
+    id,
+    user_id,
+    name,
+    token_prefix,
+    token_hash,
+    scopes,
+    created_at,
+    last_used_at
+FROM
+    access_tokens
+WHERE
+    user_id = $1
+ORDER BY
+    created_at DESC;
+
+-- name: TouchAccessToken :exec
+UPDATE
+    access_tokens
+SET
+    last_used_at = NOW()
+WHERE
+    id = $1;
+
+-- name: DeleteAccessTokenForUser :execrows
+DELETE FROM
+    access_tokens
+WHERE
+    id = $1
+    AND user_id = $2;
@@ -0,0 +1,47 @@
+-- name: ScheduleAccountDeletion :one
+INSERT INTO
+    account_deletions (user_id, keep_comments, scheduled_for)
+VALUES
+    ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    keep_comments = EXCLUDED.keep_comments,
+    requested_at = NOW(),
+    scheduled_for = EXCLUDED.scheduled_for
+RETURNING
+    user_id,
+    keep_comments,
+    requested_at,
+    scheduled_for;
+
+-- name: GetAccountDeletionForUser :one
+SELECT
+    user_id,
+    keep_comments,
+    requested_at,
+    scheduled_for
+FROM
+    account_deletions
+WHERE
+    user_id = $1;
+
+-- name: CancelAccountDeletion :execrows
+DELETE FROM
+    account_deletions
+WHERE
+    user_id = $1;
+
+-- name: GetDueAccountDeletions :many
+SELECT
+    user_id,
+    keep_comments,
+    requested_at,
+    scheduled_for
+FROM
+    account_deletions
+WHERE
+    scheduled_for <= NOW()
+ORDER BY
+    scheduled_for
+LIMIT
+    $1;
@@ -0,0 +1,122 @@
+-- name: CreateAttachment :one
+INSERT INTO
+    attachments (
+        id,
+        user_id,
+        filename,
+        content_type,
+        size,
+        width,
+        height,
+        has_thumbnail
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+    id,
+    user_id,
+    filename,
+    content_type,
+    size,
+    width,
+    height,
+    has_thumbnail,
+    created_at;
+
+-- name: GetAttachmentByID :one
+SELECT
+    id,
+    user_id,
+    filename,
+    content_type,
+    size,
+    width,
+    height,
+    has_thumbnail,
+    created_at
+FROM
+    attachments
+WHERE
+    id = $1;
+
+-- name: GetPostAttachments :many
+SELECT
+    a.id,
+    a.user_id,
+    a.filename,
+    a.content_type,
+    a.size,
+    a.width,
+    a.height,
+    a.has_thumbnail,
+    a.created_at
+FROM
+    post_attachments pa
+    JOIN attachments a ON pa.attachment_id = a.id
+WHERE
+    pa.post_id = $1
+ORDER BY
+    pa.position;
+
+-- name: DeletePostAttachments :exec
+DELETE FROM
+    post_attachments
+WHERE
+    post_id = $1;
+
+-- name: CreatePostAttachment :exec
+INSERT INTO
+    post_attachments (post_id, attachment_id, position)
+VALUES
+    ($1, $2, $3);
+
+-- name: GetOrphanedAttachments :many
+-- Attachments created before the given time that no post embeds, neither
+-- now nor in an earlier revision. Only the uploader can embed an
+-- attachment, so only their revisions are searched. Attachments of deleted
+-- accounts go regardless of age.
+SELECT
+    a.id,
+    a.user_id,
+    a.filename,
+    a.content_type,
+    a.size,
+    a.width,
+    a.height,
+    a.has_thumbnail,
+    a.created_at
+FROM
+    attachments a
+WHERE
+    (
+        a.created_at < $1
+        OR a.user_id IS NULL
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            post_attachments pa
+        WHERE
+            pa.attachment_id = a.id
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            post_revisions pr
+            JOIN posts p ON pr.post_id = p.id
+        WHERE
+            p.user_id = a.user_id
+            AND pr.content ILIKE '%attachment:' || a.id::TEXT || '%'
+    )
+ORDER BY
+    a.created_at
+LIMIT
+    $2;
+
+-- name: DeleteAttachment :exec
+DELETE FROM
+    attachments
+WHERE
+    id = $1;
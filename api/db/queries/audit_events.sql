@@ -0,0 +1,67 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO
+    audit_events (
+        event_type,
+        user_id,
+        username,
+        ip_address,
+        user_agent,
+        outcome,
+        detail
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetAuditEvents :many
+-- Empty filters match everything.
+SELECT
+    id,
+    event_type,
+    user_id,
+    username,
+    ip_address,
+    user_agent,
+    outcome,
+    detail,
+    created_at
+FROM
+    audit_events
+WHERE
+    (sqlc.arg(event_type)::text = '' OR event_type = sqlc.arg(event_type)::text)
+    AND (sqlc.arg(username)::text = '' OR username = sqlc.arg(username)::text)
+    AND (sqlc.arg(ip_address)::text = '' OR ip_address = sqlc.arg(ip_address)::text)
+    AND (sqlc.arg(outcome)::text = '' OR outcome = sqlc.arg(outcome)::text)
+    AND created_at >= sqlc.arg(since)::timestamptz
+    AND created_at < sqlc.arg(until)::timestamptz
+ORDER BY
+    created_at DESC
+LIMIT
+    sqlc.arg(row_limit)
+OFFSET
+    sqlc.arg(row_offset);
+
+-- name: GetRecentAuditEventsForUser :many
+SELECT
+    id,
+    event_type,
+    user_id,
+    username,
+    ip_address,
+    user_agent,
+    outcome,
+    detail,
+    created_at
+FROM
+    audit_events
+WHERE
+    user_id = $1
+ORDER BY
+    created_at DESC
+LIMIT
+    $2;
+
+-- name: DeleteAuditEventsBefore :execrows
+DELETE FROM
+    audit_events
+WHERE
+    created_at < $1;
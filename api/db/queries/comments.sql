@@ -9,7 +9,9 @@ RETURNING
     user_id,
     content,
     created_at,
-    updated_at;
+    updated_at,
+    deleted_at,
+    deleted_by;
 
 -- name: GetCommentByID :one
 SELECT
@@ -18,27 +20,113 @@ SELECT
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by
 FROM
     comments
 WHERE
     id = $1;
 
 -- name: GetLatestCommentsForPost :many
+-- Reactions are counted per emoji, with whether the viewer used each.
 SELECT
     c.id,
     c.content,
     c.created_at,
-    u.username AS username
+    COALESCE(u.username, '') AS username,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = sqlc.arg(viewer_id)) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = c.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     comments c
 JOIN
+    posts p ON c.post_id = p.id
+LEFT JOIN
     users u ON c.user_id = u.id
 WHERE
-    c.post_id = $1
+    c.post_id = sqlc.arg(post_id)
+    AND c.deleted_at = TO_TIMESTAMP(0)
+    AND p.deleted_at = TO_TIMESTAMP(0)
 ORDER BY
     c.created_at DESC
 LIMIT
-    $2
+    sqlc.arg('limit')
 OFFSET
-    $3;
\ No newline at end of file
+    sqlc.arg('offset');
+
+-- name: DeleteCommentsByUser :exec
+DELETE FROM
+    comments
+WHERE
+    user_id = $1;
+
+-- name: SoftDeleteComment :execrows
+UPDATE
+    comments
+SET
+    deleted_at = NOW(),
+    deleted_by = $2
+WHERE
+    id = $1
+    AND deleted_at = TO_TIMESTAMP(0);
+
+-- name: RestoreComment :execrows
+UPDATE
+    comments
+SET
+    deleted_at = TO_TIMESTAMP(0),
+    deleted_by = NULL
+WHERE
+    id = $1
+    AND deleted_at <> TO_TIMESTAMP(0);
+
+-- name: GetDeletedCommentsByUser :many
+SELECT
+    id,
+    post_id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by
+FROM
+    comments
+WHERE
+    user_id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+ORDER BY
+    deleted_at DESC;
+
+-- name: PurgeDeletedComments :execrows
+DELETE FROM
+    comments
+WHERE
+    deleted_at <> TO_TIMESTAMP(0)
+    AND deleted_at < $1;
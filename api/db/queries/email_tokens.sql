@@ -0,0 +1,40 @@
+-- name: CreateEmailToken :one
+INSERT INTO
+    email_tokens (token_hash, user_id, purpose, email, expires_at)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    token_hash,
+    user_id,
+    purpose,
+    email,
+    created_at,
+    expires_at;
+
+-- name: ConsumeEmailToken :one
+DELETE FROM
+    email_tokens
+WHERE
+    token_hash = $1
+    AND purpose = $2
+    AND expires_at > NOW()
+RETURNING
+    token_hash,
+    user_id,
+    purpose,
+    email,
+    created_at,
+    expires_at;
+
+-- name: DeleteEmailTokensForUser :exec
+DELETE FROM
+    email_tokens
+WHERE
+    user_id = $1
+    AND purpose = $2;
+
+-- name: DeleteExpiredEmailTokens :execrows
+DELETE FROM
+    email_tokens
+WHERE
+    expires_at <= NOW();
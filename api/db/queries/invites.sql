@@ -0,0 +1,126 @@
+-- name: CreateInvite :one
+INSERT INTO
+    invites (code, created_by, max_uses, expires_at)
+VALUES
+    ($1, $2, $3, $4)
+RETURNING
+    id,
+    code,
+    created_by,
+    max_uses,
+    uses,
+    expires_at,
+    created_at;
+
+-- name: GetInvitesCreatedBy :many
+SELECT
+    id,
+    code,
+    created_by,
+    max_uses,
+    uses,
+    expires_at,
+    created_at
+FROM
+    invites
+WHERE
+    created_by = $1
+ORDER BY
+    created_at DESC;
+
+-- name: CountInviteUsesCreatedBy :one
+SELECT
+    COALESCE(
+        SUM(
+            CASE
+                WHEN expires_at > NOW() THEN max_uses
+                ELSE uses
+            END
+        ),
+        0
+    )::BIGINT AS committed
+FROM
+    invites
+WHERE
+    created_by = $1;
+
+-- name: RedeemInvite :one
+UPDATE
+    invites
+SET
+    uses = uses + 1
+WHERE
+    code = $1
+    AND uses < max_uses
+    AND expires_at > NOW()
+RETURNING
+    id,
+    code,
+    created_by,
+    max_uses,
+    uses,
+    expires_at,
+    created_at;
+
+-- name: RevokeInvite :execrows
+UPDATE
+    invites
+SET
+    expires_at = NOW()
+WHERE
+    id = $1
+    AND created_by = $2
+    AND expires_at > NOW();
+
+-- name: CreateInviteRedemption :exec
+INSERT INTO
+    invite_redemptions (user_id, invite_id, invited_by)
+VALUES
+    ($1, $2, $3);
+
+-- name: GetInviteRedemptions :many
+SELECT
+    ir.user_id,
+    u.username,
+    ir.invited_by,
+    COALESCE(inviter.username, '') AS invited_by_username,
+    i.code,
+    ir.redeemed_at
+FROM
+    invite_redemptions ir
+    JOIN users u ON ir.user_id = u.id
+    JOIN invites i ON ir.invite_id = i.id
+    LEFT JOIN users inviter ON ir.invited_by = inviter.id
+ORDER BY
+    ir.redeemed_at DESC
+LIMIT
+    $1
+OFFSET
+    $2;
+
+-- name: GetInviteQuota :one
+SELECT
+    quota
+FROM
+    invite_quotas
+WHERE
+    user_id = $1;
+
+-- name: GetInviteQuotaForUpdate :one
+SELECT
+    quota
+FROM
+    invite_quotas
+WHERE
+    user_id = $1
+FOR UPDATE;
+
+-- name: SetInviteQuota :exec
+INSERT INTO
+    invite_quotas (user_id, quota)
+VALUES
+    ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    quota = EXCLUDED.quota,
+    updated_at = NOW();
@@ -0,0 +1,75 @@
+-- name: RecordLoginFailure :one
+INSERT INTO
+    login_attempts (kind, key, failures, last_failure_at)
+VALUES
+    ($1, $2, 1, NOW())
+ON CONFLICT (kind, key) DO UPDATE
+SET
+    failures = CASE
+        WHEN login_attempts.last_failure_at < NOW() - INTERVAL '1 day' THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING
+    kind,
+    key,
+    failures,
+    last_failure_at,
+    locked_until;
+
+-- name: SetLoginLockedUntil :exec
+UPDATE
+    login_attempts
+SET
+    locked_until = $3
+WHERE
+    kind = $1
+    AND key = $2;
+
+-- name: GetActiveLoginLocks :many
+SELECT
+    kind,
+    key,
+    failures,
+    last_failure_at,
+    locked_until
+FROM
+    login_attempts
+WHERE
+    locked_until > NOW()
+    AND (
+        (kind = 'username' AND key = sqlc.arg(username)::text)
+        OR (kind = 'ip' AND key = sqlc.arg(ip_address)::text)
+    );
+
+-- name: GetLockedLoginAttempts :many
+SELECT
+    kind,
+    key,
+    failures,
+    last_failure_at,
+    locked_until
+FROM
+    login_attempts
+WHERE
+    locked_until > NOW()
+ORDER BY
+    locked_until DESC
+LIMIT
+    $1
+OFFSET
+    $2;
+
+-- name: ClearLoginAttempts :execrows
+DELETE FROM
+    login_attempts
+WHERE
+    kind = $1
+    AND key = $2;
+
+-- name: DeleteStaleLoginAttempts :execrows
+DELETE FROM
+    login_attempts
+WHERE
+    last_failure_at < NOW() - INTERVAL '1 day'
+    AND locked_until <= NOW();
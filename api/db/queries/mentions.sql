@@ -0,0 +1,46 @@
+-- name: CreateMention :exec
+INSERT INTO
+    mentions (user_id, post_id, comment_id)
+VALUES
+    ($1, $2, $3);
+
+-- name: DeleteMentionsForSource :exec
+-- Removes the mentions made by a post itself, or by one of its comments
+-- when comment_id is set.
+DELETE FROM
+    mentions
+WHERE
+    post_id = $1
+    AND comment_id IS NOT DISTINCT FROM $2;
+
+-- name: GetLatestMentionsForUser :many
+-- Mentions of a user in published posts and their comments, newest first.
+SELECT
+    m.id,
+    m.post_id,
+    m.comment_id,
+    COALESCE(c.content, p.content) AS content,
+    m.created_at,
+    pu.username AS post_author,
+    cu.username AS comment_author,
+    p.slug
+FROM
+    mentions m
+    JOIN posts p ON m.post_id = p.id
+    JOIN users pu ON p.user_id = pu.id
+    LEFT JOIN comments c ON m.comment_id = c.id
+    LEFT JOIN users cu ON c.user_id = cu.id
+WHERE
+    m.user_id = $1
+    AND p.status = 'published'
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        m.comment_id IS NULL
+        OR c.deleted_at = TO_TIMESTAMP(0)
+    )
+ORDER BY
+    m.created_at DESC
+LIMIT
+    $2
+OFFSET
+    $3;
@@ -0,0 +1,25 @@
+-- name: CreatePostRevision :exec
+INSERT INTO
+    post_revisions (post_id, revision, content, created_at)
+SELECT
+    sqlc.arg(post_id),
+    COALESCE(MAX(revision), 0) + 1,
+    sqlc.arg(content),
+    sqlc.arg(created_at)
+FROM
+    post_revisions
+WHERE
+    post_id = sqlc.arg(post_id);
+
+-- name: GetPostRevisions :many
+SELECT
+    post_id,
+    revision,
+    content,
+    created_at
+FROM
+    post_revisions
+WHERE
+    post_id = $1
+ORDER BY
+    revision;
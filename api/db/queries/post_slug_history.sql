@@ -0,0 +1,50 @@
+-- name: GetPostIDBySlugHistory :one
+SELECT
+    post_id
+FROM
+    post_slug_history
+WHERE
+    user_id = $1
+    AND slug = $2;
+
+-- name: CreatePostSlugRedirect :exec
+INSERT INTO
+    post_slug_history (user_id, slug, post_id)
+VALUES
+    ($1, $2, $3)
+ON CONFLICT (user_id, slug) DO UPDATE
+SET
+    post_id = EXCLUDED.post_id,
+    created_at = NOW();
+
+-- name: DeletePostSlugRedirect :exec
+DELETE FROM
+    post_slug_history
+WHERE
+    user_id = $1
+    AND slug = $2;
+
+-- name: IsPostSlugTaken :one
+-- Whether the author already uses slug for a post other than post_id,
+-- either currently or as a redirect.
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            posts
+        WHERE
+            user_id = sqlc.arg(user_id)
+            AND slug = sqlc.arg(slug)
+            AND id <> sqlc.arg(post_id)
+    )
+    OR EXISTS (
+        SELECT
+            1
+        FROM
+            post_slug_history
+        WHERE
+            user_id = sqlc.arg(user_id)
+            AND slug = sqlc.arg(slug)
+            AND post_id <> sqlc.arg(post_id)
+    ) AS taken;
@@ -1,14 +1,43 @@
 -- name: CreatePost :one
 INSERT INTO
-    posts (user_id, content)
+    posts (
+        user_id,
+        content,
+        title,
+        slug,
+        status,
+        publish_at,
+        kind,
+        excerpt,
+        word_count
+    )
 VALUES
-    ($1, $2)
+    (
+        sqlc.arg(user_id),
+        sqlc.arg(content),
+        sqlc.arg(title),
+        sqlc.arg(slug),
+        sqlc.arg(status),
+        GREATEST(sqlc.arg(publish_at)::TIMESTAMPTZ, NOW()),
+        sqlc.arg(kind),
+        sqlc.arg(excerpt),
+        sqlc.arg(word_count)
+    )
 RETURNING
     id,
     user_id,
     content,
     created_at,
-    updated_at;
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count;
 
 -- name: GetPostByID :one
 SELECT
@@ -16,25 +45,318 @@ SELECT
     user_id,
     content,
     created_at,
-    updated_at
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
 FROM
     posts
 WHERE
     id = $1;
 
 -- name: GetLatestPosts :many
+-- Published posts, newest first. The viewer also sees their own drafts and
+-- scheduled posts. Articles come with their excerpt in place of the content.
+-- Reactions are counted per emoji, with whether the viewer used each.
 SELECT 
     p.id,
-    p.content,
+    (CASE WHEN p.kind = 'article' THEN p.excerpt ELSE p.content END)::TEXT AS content,
     p.created_at,
-    u.username
+    u.username,
+    p.updated_at > p.publish_at AS edited,
+    p.title,
+    p.slug,
+    p.status,
+    p.publish_at,
+    p.kind,
+    p.word_count,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'id', a.id,
+                        'filename', a.filename,
+                        'content_type', a.content_type,
+                        'size', a.size,
+                        'width', a.width,
+                        'height', a.height,
+                        'has_thumbnail', a.has_thumbnail
+                    )
+                    ORDER BY pa.position
+                )
+            FROM
+                post_attachments pa
+                JOIN attachments a ON pa.attachment_id = a.id
+            WHERE
+                pa.post_id = p.id
+        ),
+        '[]'
+    )::JSON AS attachments,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = sqlc.arg(viewer_id)) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = p.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM 
     posts p
 JOIN 
     users u ON p.user_id = u.id
+WHERE
+    p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = sqlc.arg(viewer_id)
+    )
 ORDER BY 
-    p.created_at DESC
+    p.publish_at DESC
 LIMIT 
-    $1
+    sqlc.arg('limit')
 OFFSET 
-    $2;
\ No newline at end of file
+    sqlc.arg('offset');
+
+-- name: CountPostsByUser :one
+SELECT
+    COUNT(*)
+FROM
+    posts
+WHERE
+    user_id = $1;
+
+-- name: GetPostByIDForUpdate :one
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    id = $1
+FOR UPDATE;
+
+-- name: UpdatePostContent :one
+UPDATE
+    posts
+SET
+    content = $2,
+    excerpt = $3,
+    word_count = $4
+WHERE
+    id = $1
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count;
+
+-- name: SoftDeletePost :execrows
+UPDATE
+    posts
+SET
+    deleted_at = NOW(),
+    deleted_by = $2
+WHERE
+    id = $1
+    AND deleted_at = TO_TIMESTAMP(0);
+
+-- name: RestorePost :execrows
+UPDATE
+    posts
+SET
+    deleted_at = TO_TIMESTAMP(0),
+    deleted_by = NULL
+WHERE
+    id = $1
+    AND deleted_at <> TO_TIMESTAMP(0);
+
+-- name: GetDeletedPostsByUser :many
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND deleted_at <> TO_TIMESTAMP(0)
+ORDER BY
+    deleted_at DESC;
+
+-- name: PurgeDeletedPosts :execrows
+DELETE FROM
+    posts
+WHERE
+    deleted_at <> TO_TIMESTAMP(0)
+    AND deleted_at < $1;
+
+-- name: GetPostBySlug :one
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND slug = $2;
+
+-- name: UpdatePostPermalink :one
+UPDATE
+    posts
+SET
+    title = $2,
+    slug = $3
+WHERE
+    id = $1
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count;
+
+-- name: GetUnpublishedPostsByUser :many
+SELECT
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count
+FROM
+    posts
+WHERE
+    user_id = $1
+    AND status <> 'published'
+    AND deleted_at = TO_TIMESTAMP(0)
+ORDER BY
+    updated_at DESC;
+
+-- name: UpdateDraft :one
+-- publish_at is never set in the past, so that a post published now does
+-- not count as edited since.
+UPDATE
+    posts
+SET
+    content = sqlc.arg(content),
+    excerpt = sqlc.arg(excerpt),
+    word_count = sqlc.arg(word_count),
+    title = sqlc.arg(title),
+    status = sqlc.arg(status),
+    publish_at = GREATEST(sqlc.arg(publish_at)::TIMESTAMPTZ, NOW())
+WHERE
+    id = sqlc.arg(id)
+RETURNING
+    id,
+    user_id,
+    content,
+    created_at,
+    updated_at,
+    deleted_at,
+    deleted_by,
+    title,
+    slug,
+    status,
+    publish_at,
+    kind,
+    excerpt,
+    word_count;
+
+-- name: PublishDuePosts :execrows
+UPDATE
+    posts
+SET
+    status = 'published'
+WHERE
+    status = 'scheduled'
+    AND publish_at <= NOW()
+    AND deleted_at = TO_TIMESTAMP(0);
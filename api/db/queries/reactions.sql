@@ -0,0 +1,51 @@
+-- name: CreateReaction :execrows
+INSERT INTO
+    reactions (post_id, comment_id, user_id, emoji)
+VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteReaction :execrows
+-- Takes back a reaction. Emoji are compared without variation selectors,
+-- so that a reaction stored as ❤ is found when ❤️ is toggled.
+DELETE FROM
+    reactions
+WHERE
+    target_id = $1
+    AND user_id = $2
+    AND REPLACE(emoji, CHR(65039), '') = REPLACE(sqlc.arg(emoji), CHR(65039), '');
+
+-- name: GetReactionCounts :many
+-- How many users reacted to a post or comment with each emoji, most used
+-- first, and whether the viewer is one of them.
+SELECT
+    emoji,
+    COUNT(*) AS count,
+    BOOL_OR(user_id = sqlc.arg(viewer_id)) AS reacted
+FROM
+    reactions
+WHERE
+    target_id = sqlc.arg(target_id)
+GROUP BY
+    emoji
+ORDER BY
+    count DESC,
+    MIN(created_at);
+
+-- name: GetReactionsForTarget :many
+-- Who reacted to a post or comment and with what, oldest first.
+SELECT
+    u.username,
+    r.emoji,
+    r.created_at
+FROM
+    reactions r
+    JOIN users u ON r.user_id = u.id
+WHERE
+    r.target_id = $1
+ORDER BY
+    r.created_at
+LIMIT
+    $2
+OFFSET
+    $3;
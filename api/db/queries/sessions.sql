@@ -0,0 +1,88 @@
+-- name: CreateSession :one
+INSERT INTO
+    sessions (user_id, user_agent, ip_address, expires_at, two_factor_pending)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    id,
+    user_id,
+    user_agent,
+    ip_address,
+    created_at,
+    last_seen_at,
+    expires_at,
+    two_factor_pending;
+
+-- name: GetActiveSessionByID :one
+SELECT
+    id,
+    user_id,
+    user_agent,
+    ip_address,
+    created_at,
+    last_seen_at,
+    expires_at,
+    two_factor_pending
+FROM
+    sessions
+WHERE
+    id = $1
+    AND expires_at > NOW();
+
+-- name: GetActiveSessionsForUser :many
+SELECT
+    id,
+    user_id,
+    user_agent,
+    ip_address,
+    created_at,
+    last_seen_at,
+    expires_at,
+    two_factor_pending
+FROM
+    sessions
+WHERE
+    user_id = $1
+    AND expires_at > NOW()
+ORDER BY
+    last_seen_at DESC;
+
+-- name: TouchSession :exec
+UPDATE
+    sessions
+SET
+    last_seen_at = NOW()
+WHERE
+    id = $1;
+
+-- name: DeleteSession :exec
+DELETE FROM
+    sessions
+WHERE
+    id = $1;
+
+-- name: DeleteSessionForUser :execrows
+DELETE FROM
+    sessions
+WHERE
+    id = $1
+    AND user_id = $2;
+
+-- name: DeleteOtherSessionsForUser :execrows
+DELETE FROM
+    sessions
+WHERE
+    user_id = $1
+    AND id <> $2;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM
+    sessions
+WHERE
+    expires_at <= NOW();
+
+-- name: DeleteSessionsForUser :execrows
+DELETE FROM
+    sessions
+WHERE
+    user_id = $1;
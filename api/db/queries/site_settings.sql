@@ -0,0 +1,17 @@
+-- name: GetSiteSetting :one
+SELECT
+    value
+FROM
+    site_settings
+WHERE
+    key = $1;
+
+-- name: UpsertSiteSetting :exec
+INSERT INTO
+    site_settings (key, value)
+VALUES
+    ($1, $2)
+ON CONFLICT (key) DO UPDATE
+SET
+    value = EXCLUDED.value,
+    updated_at = NOW();
@@ -0,0 +1,21 @@
+-- name: SpendChallenge :execrows
+INSERT INTO
+    spent_challenges (nonce, purpose, expires_at)
+VALUES
+    ($1, $2, $3)
+ON CONFLICT (nonce) DO NOTHING;
+
+-- name: CountChallengesSpentSince :one
+SELECT
+    COUNT(*)
+FROM
+    spent_challenges
+WHERE
+    purpose = $1
+    AND spent_at > sqlc.arg(since);
+
+-- name: DeleteExpiredChallenges :execrows
+DELETE FROM
+    spent_challenges
+WHERE
+    expires_at < NOW();
@@ -0,0 +1,164 @@
+-- name: UpsertTag :one
+INSERT INTO
+    tags (name)
+VALUES
+    ($1)
+ON CONFLICT (name) DO UPDATE
+SET
+    name = EXCLUDED.name
+RETURNING
+    id;
+
+-- name: DeletePostTags :exec
+DELETE FROM
+    post_tags
+WHERE
+    post_id = $1;
+
+-- name: CreatePostTag :exec
+INSERT INTO
+    post_tags (post_id, tag_id)
+VALUES
+    ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetPostTags :many
+SELECT
+    t.name
+FROM
+    post_tags pt
+    JOIN tags t ON pt.tag_id = t.id
+WHERE
+    pt.post_id = $1
+ORDER BY
+    t.name;
+
+-- name: GetLatestPostsByTag :many
+-- Like GetLatestPosts, limited to posts carrying the tag.
+SELECT 
+    p.id,
+    (CASE WHEN p.kind = 'article' THEN p.excerpt ELSE p.content END)::TEXT AS content,
+    p.created_at,
+    u.username,
+    p.updated_at > p.publish_at AS edited,
+    p.title,
+    p.slug,
+    p.status,
+    p.publish_at,
+    p.kind,
+    p.word_count,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'id', a.id,
+                        'filename', a.filename,
+                        'content_type', a.content_type,
+                        'size', a.size,
+                        'width', a.width,
+                        'height', a.height,
+                        'has_thumbnail', a.has_thumbnail
+                    )
+                    ORDER BY pa.position
+                )
+            FROM
+                post_attachments pa
+                JOIN attachments a ON pa.attachment_id = a.id
+            WHERE
+                pa.post_id = p.id
+        ),
+        '[]'
+    )::JSON AS attachments,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = sqlc.arg(viewer_id)) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = p.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
+FROM 
+    posts p
+JOIN 
+    users u ON p.user_id = u.id
+JOIN
+    post_tags pt ON pt.post_id = p.id
+JOIN
+    tags t ON pt.tag_id = t.id
+WHERE
+    t.name = sqlc.arg(tag)
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = sqlc.arg(viewer_id)
+    )
+ORDER BY 
+    p.publish_at DESC
+LIMIT 
+    sqlc.arg('limit')
+OFFSET 
+    sqlc.arg('offset');
+
+-- name: GetTrendingTags :many
+-- Tags by how many published posts used them since the given time.
+SELECT
+    t.name,
+    COUNT(*) AS posts
+FROM
+    tags t
+    JOIN post_tags pt ON pt.tag_id = t.id
+    JOIN posts p ON pt.post_id = p.id
+WHERE
+    p.status = 'published'
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND p.publish_at >= $1
+GROUP BY
+    t.name
+ORDER BY
+    posts DESC,
+    t.name
+LIMIT
+    $2;
+
+-- name: SearchTagsByPrefix :many
+-- Tags whose name matches a LIKE pattern, most used first. Only published
+-- posts count, so tags used only in drafts are not suggested.
+SELECT
+    t.name,
+    COUNT(*) AS posts
+FROM
+    tags t
+    JOIN post_tags pt ON pt.tag_id = t.id
+    JOIN posts p ON pt.post_id = p.id
+WHERE
+    t.name LIKE $1
+    AND p.status = 'published'
+    AND p.deleted_at = TO_TIMESTAMP(0)
+GROUP BY
+    t.name
+ORDER BY
+    posts DESC,
+    t.name
+LIMIT
+    $2;
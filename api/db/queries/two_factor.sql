@@ -0,0 +1,73 @@
+-- name: UpsertUserTOTP :one
+INSERT INTO
+    user_totp (user_id, secret)
+VALUES
+    ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    secret = EXCLUDED.secret,
+    enabled = FALSE,
+    last_used_step = 0
+RETURNING
+    user_id,
+    secret,
+    enabled,
+    last_used_step,
+    created_at,
+    updated_at;
+
+-- name: GetUserTOTP :one
+SELECT
+    user_id,
+    secret,
+    enabled,
+    last_used_step,
+    created_at,
+    updated_at
+FROM
+    user_totp
+WHERE
+    user_id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE
+    user_totp
+SET
+    enabled = TRUE,
+    last_used_step = $2
+WHERE
+    user_id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE
+    user_totp
+SET
+    last_used_step = $2
+WHERE
+    user_id = $1
+    AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM
+    user_totp
+WHERE
+    user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO
+    recovery_codes (user_id, code_hash)
+VALUES
+    ($1, $2);
+
+-- name: DeleteRecoveryCodesForUser :exec
+DELETE FROM
+    recovery_codes
+WHERE
+    user_id = $1;
+
+-- name: ConsumeRecoveryCode :execrows
+DELETE FROM
+    recovery_codes
+WHERE
+    user_id = $1
+    AND code_hash = $2;
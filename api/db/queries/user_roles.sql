@@ -0,0 +1,44 @@
+-- name: GetUserRoles :many
+SELECT
+    role
+FROM
+    user_roles
+WHERE
+    user_id = $1
+ORDER BY
+    role;
+
+-- name: GetRoleAssignments :many
+SELECT
+    ur.user_id,
+    u.username,
+    ur.role,
+    ur.granted_at
+FROM
+    user_roles ur
+    JOIN users u ON ur.user_id = u.id
+ORDER BY
+    ur.role,
+    u.username;
+
+-- name: CountRoleHolders :one
+SELECT
+    COUNT(*)
+FROM
+    user_roles
+WHERE
+    role = $1;
+
+-- name: GrantUserRole :execrows
+INSERT INTO
+    user_roles (user_id, role)
+VALUES
+    ($1, $2)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RevokeUserRole :execrows
+DELETE FROM
+    user_roles
+WHERE
+    user_id = $1
+    AND role = $2;
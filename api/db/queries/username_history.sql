@@ -0,0 +1,45 @@
+-- name: CreateUsernameChange :exec
+INSERT INTO
+    username_history (user_id, old_username, new_username)
+VALUES
+    ($1, $2, $3);
+
+-- name: GetUsernameHistory :many
+SELECT
+    id,
+    user_id,
+    old_username,
+    new_username,
+    changed_at
+FROM
+    username_history
+WHERE
+    user_id = $1
+ORDER BY
+    changed_at DESC;
+
+-- name: GetLastUsernameChange :one
+SELECT
+    changed_at
+FROM
+    username_history
+WHERE
+    user_id = $1
+ORDER BY
+    changed_at DESC
+LIMIT
+    1;
+
+-- name: GetPreviousUsernameOwner :one
+-- The account that most recently gave up the username after since.
+SELECT
+    user_id
+FROM
+    username_history
+WHERE
+    old_username = $1
+    AND changed_at > sqlc.arg(since)
+ORDER BY
+    changed_at DESC
+LIMIT
+    1;
@@ -8,7 +8,9 @@ RETURNING
     username,
     password_hash,
     created_at,
-    updated_at;
+    updated_at,
+    email,
+    email_verified;
 
 -- name: GetUserByID :one
 SELECT
@@ -16,7 +18,9 @@ SELECT
     username,
     password_hash,
     created_at,
-    updated_at
+    updated_at,
+    email,
+    email_verified
 FROM
     users
 WHERE
@@ -28,7 +32,9 @@ SELECT
     username,
     password_hash,
     created_at,
-    updated_at
+    updated_at,
+    email,
+    email_verified
 FROM
     users
 WHERE
@@ -46,33 +52,162 @@ SELECT
     ) AS user_exists;
 
 -- name: GetLatestUserActivity :many
+-- A user's posts and comments, newest first. Only the author of a draft or
+-- scheduled post sees it and the comments on it. Articles come with their
+-- excerpt in place of the content. Reactions are counted per emoji, with
+-- whether the viewer used each.
 WITH user_info AS (
     SELECT id
     FROM users
-    WHERE username = $1
+    WHERE username = sqlc.arg(username)
 )
 SELECT
     p.id AS post_id,
-    p.content,
-    p.created_at AS action_time,
-    'post' AS action_type
+    (CASE WHEN p.kind = 'article' THEN p.excerpt ELSE p.content END)::TEXT AS content,
+    p.publish_at AS action_time,
+    'post' AS action_type,
+    p.status,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = sqlc.arg(viewer_id)) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = p.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     posts p
 WHERE
     p.user_id = (SELECT id FROM user_info)
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = sqlc.arg(viewer_id)
+    )
 UNION ALL
 SELECT
     c.id AS post_id,
     c.content,
     c.created_at AS action_time,
-    'comment' AS action_type
+    'comment' AS action_type,
+    p.status,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = sqlc.arg(viewer_id)) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = c.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     comments c
+    JOIN posts p ON c.post_id = p.id
 WHERE
     c.user_id = (SELECT id FROM user_info)
+    AND c.deleted_at = TO_TIMESTAMP(0)
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = sqlc.arg(viewer_id)
+    )
 ORDER BY
     action_time DESC
 LIMIT 
-    $2
+    sqlc.arg('limit')
 OFFSET 
-    $3;
\ No newline at end of file
+    sqlc.arg('offset');
+
+-- name: UpdateUserPassword :exec
+UPDATE
+    users
+SET
+    password_hash = $2
+WHERE
+    id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM
+    users
+WHERE
+    id = $1;
+
+-- name: GetUserByVerifiedEmail :one
+SELECT
+    id,
+    username,
+    password_hash,
+    created_at,
+    updated_at,
+    email,
+    email_verified
+FROM
+    users
+WHERE
+    LOWER(email) = LOWER($1)
+    AND email_verified;
+
+-- name: SetUserEmail :exec
+UPDATE
+    users
+SET
+    email = $2,
+    email_verified = FALSE
+WHERE
+    id = $1;
+
+-- name: MarkUserEmailVerified :execrows
+UPDATE
+    users
+SET
+    email_verified = TRUE
+WHERE
+    id = $1
+    AND email = $2;
+
+-- name: UpdateUsername :exec
+UPDATE
+    users
+SET
+    username = $2
+WHERE
+    id = $1;
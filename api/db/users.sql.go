@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"encore.dev/types/uuid"
@@ -41,7 +42,9 @@ RETURNING
     username,
     password_hash,
     created_at,
-    updated_at
+    updated_at,
+    email,
+    email_verified
 `
 
 type CreateUserParams struct {
@@ -58,10 +61,24 @@ func (q *Queries) CreateUser(ctx context.Context, db DBTX, arg CreateUserParams)
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.EmailVerified,
 	)
 	return &i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM
+    users
+WHERE
+    id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, db DBTX, id uuid.UUID) error {
+	_, err := db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const getLatestUserActivity = `-- name: GetLatestUserActivity :many
 WITH user_info AS (
     SELECT id
@@ -70,33 +87,104 @@ WITH user_info AS (
 )
 SELECT
     p.id AS post_id,
-    p.content,
-    p.created_at AS action_time,
-    'post' AS action_type
+    (CASE WHEN p.kind = 'article' THEN p.excerpt ELSE p.content END)::TEXT AS content,
+    p.publish_at AS action_time,
+    'post' AS action_type,
+    p.status,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = $2) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = p.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     posts p
 WHERE
     p.user_id = (SELECT id FROM user_info)
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = $2
+    )
 UNION ALL
 SELECT
     c.id AS post_id,
     c.content,
     c.created_at AS action_time,
-    'comment' AS action_type
+    'comment' AS action_type,
+    p.status,
+    COALESCE(
+        (
+            SELECT
+                JSON_AGG(
+                    JSON_BUILD_OBJECT(
+                        'emoji', rc.emoji,
+                        'count', rc.count,
+                        'reacted', rc.reacted
+                    )
+                    ORDER BY rc.count DESC, rc.first_reacted_at
+                )
+            FROM
+                (
+                    SELECT
+                        r.emoji,
+                        COUNT(*) AS count,
+                        BOOL_OR(r.user_id = $2) AS reacted,
+                        MIN(r.created_at) AS first_reacted_at
+                    FROM
+                        reactions r
+                    WHERE
+                        r.target_id = c.id
+                    GROUP BY
+                        r.emoji
+                ) rc
+        ),
+        '[]'
+    )::JSON AS reactions
 FROM
     comments c
+    JOIN posts p ON c.post_id = p.id
 WHERE
     c.user_id = (SELECT id FROM user_info)
+    AND c.deleted_at = TO_TIMESTAMP(0)
+    AND p.deleted_at = TO_TIMESTAMP(0)
+    AND (
+        p.status = 'published'
+        OR p.user_id = $2
+    )
 ORDER BY
     action_time DESC
 LIMIT 
-    $2
-OFFSET 
     $3
+OFFSET 
+    $4
 `
 
 type GetLatestUserActivityParams struct {
 	Username string
+	ViewerID uuid.UUID
 	Limit    int32
 	Offset   int32
 }
@@ -106,10 +194,21 @@ type GetLatestUserActivityRow struct {
 	Content    string
 	ActionTime time.Time
 	ActionType string
+	Status     string
+	Reactions  json.RawMessage
 }
 
+// A user's posts and comments, newest first. Only the author of a draft or
+// scheduled post sees it and the comments on it. Articles come with their
+// excerpt in place of the content. Reactions are counted per emoji, with
+// whether the viewer used each.
 func (q *Queries) GetLatestUserActivity(ctx context.Context, db DBTX, arg GetLatestUserActivityParams) ([]*GetLatestUserActivityRow, error) {
-	rows, err := db.QueryContext(ctx, getLatestUserActivity, arg.Username, arg.Limit, arg.Offset)
+	rows, err := db.QueryContext(ctx, getLatestUserActivity,
+		arg.Username,
+		arg.ViewerID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +221,8 @@ func (q *Queries) GetLatestUserActivity(ctx context.Context, db DBTX, arg GetLat
 			&i.Content,
 			&i.ActionTime,
 			&i.ActionType,
+			&i.Status,
+			&i.Reactions,
 		); err != nil {
 			return nil, err
 		}
@@ -142,7 +243,9 @@ SELECT
     username,
     password_hash,
     created_at,
-    updated_at
+    updated_at,
+    email,
+    email_verified
 FROM
     users
 WHERE
@@ -158,6 +261,8 @@ func (q *Queries) GetUserByID(ctx context.Context, db DBTX, id uuid.UUID) (*User
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.EmailVerified,
 	)
 	return &i, err
 }
@@ -168,7 +273,9 @@ SELECT
     username,
     password_hash,
     created_at,
-    updated_at
+    updated_at,
+    email,
+    email_verified
 FROM
     users
 WHERE
@@ -184,6 +291,120 @@ func (q *Queries) GetUserByUsername(ctx context.Context, db DBTX, username strin
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.EmailVerified,
+	)
+	return &i, err
+}
+
+const getUserByVerifiedEmail = `-- name: GetUserByVerifiedEmail :one
+SELECT
+    id,
+    username,
+    password_hash,
+    created_at,
+    updated_at,
+    email,
+    email_verified
+FROM
+    users
+WHERE
+    LOWER(email) = LOWER($1)
+    AND email_verified
+`
+
+func (q *Queries) GetUserByVerifiedEmail(ctx context.Context, db DBTX, lower string) (*User, error) {
+	row := db.QueryRowContext(ctx, getUserByVerifiedEmail, lower)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.EmailVerified,
 	)
 	return &i, err
 }
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :execrows
+UPDATE
+    users
+SET
+    email_verified = TRUE
+WHERE
+    id = $1
+    AND email = $2
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, db DBTX, arg MarkUserEmailVerifiedParams) (int64, error) {
+	result, err := db.ExecContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserEmail = `-- name: SetUserEmail :exec
+UPDATE
+    users
+SET
+    email = $2,
+    email_verified = FALSE
+WHERE
+    id = $1
+`
+
+type SetUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) SetUserEmail(ctx context.Context, db DBTX, arg SetUserEmailParams) error {
+	_, err := db.ExecContext(ctx, setUserEmail, arg.ID, arg.Email)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE
+    users
+SET
+    password_hash = $2
+WHERE
+    id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, db DBTX, arg UpdateUserPasswordParams) error {
+	_, err := db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const updateUsername = `-- name: UpdateUsername :exec
+UPDATE
+    users
+SET
+    username = $2
+WHERE
+    id = $1
+`
+
+type UpdateUsernameParams struct {
+	ID       uuid.UUID
+	Username string
+}
+
+func (q *Queries) UpdateUsername(ctx context.Context, db DBTX, arg UpdateUsernameParams) error {
+	_, err := db.ExecContext(ctx, updateUsername, arg.ID, arg.Username)
+	return err
+}
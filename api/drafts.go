@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/types/uuid"
+)
+
+// postPublished is the status of posts everyone can read.
+const postPublished = "published"
+
+type GetUnpublishedPostsByUserResult struct {
+	Posts []db.Post `json:"posts"`
+}
+
+//encore:api private method=GET path=/api/post/unpublished/:userID
+func GetUnpublishedPostsByUser(ctx context.Context, userID uuid.UUID) (*GetUnpublishedPostsByUserResult, error) {
+	rows, err := db.New().GetUnpublishedPostsByUser(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetUnpublishedPostsByUserResult{
+		Posts: make([]db.Post, 0),
+	}
+	for _, r := range rows {
+		res.Posts = append(res.Posts, *r)
+	}
+
+	return res, nil
+}
+
+type UpdateDraftParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Content   string
+	Excerpt   string
+	WordCount int32
+	Title     string
+	Status    string
+	PublishAt time.Time
+}
+
+// UpdateDraft changes a draft or scheduled post, and publishes or schedules
+// it depending on Status. It fails with NotFound if the post is deleted,
+// with PermissionDenied unless it belongs to UserID and with
+// FailedPrecondition if it is already published.
+//
+//encore:api private method=POST path=/api/post/draft
+func UpdateDraft(ctx context.Context, params UpdateDraftParams) (*db.Post, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	post, err := q.GetPostByIDForUpdate(ctx, tx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.DeletedAt.After(time.Unix(0, 0)) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "post is deleted"}
+	}
+
+	if post.UserID != params.UserID {
+		return nil, &errs.Error{Code: errs.PermissionDenied, Message: "not the author of the post"}
+	}
+
+	if post.Status == postPublished {
+		return nil, &errs.Error{Code: errs.FailedPrecondition, Message: "post is already published"}
+	}
+
+	post, err = q.UpdateDraft(ctx, tx, db.UpdateDraftParams{
+		Content:   params.Content,
+		Excerpt:   params.Excerpt,
+		WordCount: params.WordCount,
+		Title:     params.Title,
+		Status:    params.Status,
+		PublishAt: params.PublishAt,
+		ID:        post.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+var _ = cron.NewJob("publish-scheduled-posts", cron.JobConfig{
+	Title:    "Publish scheduled posts that are due",
+	Every:    1 * cron.Minute,
+	Endpoint: PublishDuePosts,
+})
+
+type PublishDuePostsResult struct {
+	Published int64 `json:"published"`
+}
+
+//encore:api private method=POST path=/api/post/publish-due
+func PublishDuePosts(ctx context.Context) (*PublishDuePostsResult, error) {
+	res := new(PublishDuePostsResult)
+	var err error
+	res.Published, err = db.New().PublishDuePosts(ctx, markblogdb.Stdlib())
+	return res, err
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+)
+
+//encore:api private method=GET path=/api/user/verified-email/:email
+func GetUserByVerifiedEmail(ctx context.Context, email string) (*db.User, error) {
+	return db.New().GetUserByVerifiedEmail(ctx, markblogdb.Stdlib(), email)
+}
+
+//encore:api private method=POST path=/api/user/email
+func SetUserEmail(ctx context.Context, params db.SetUserEmailParams) error {
+	return db.New().SetUserEmail(ctx, markblogdb.Stdlib(), params)
+}
+
+type MarkUserEmailVerifiedResult struct {
+	Verified bool `json:"verified"`
+}
+
+//encore:api private method=POST path=/api/user/email/verify
+func MarkUserEmailVerified(ctx context.Context, params db.MarkUserEmailVerifiedParams) (*MarkUserEmailVerifiedResult, error) {
+	n, err := db.New().MarkUserEmailVerified(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkUserEmailVerifiedResult{Verified: n > 0}, nil
+}
+
+//encore:api private method=POST path=/api/email-token
+func CreateEmailToken(ctx context.Context, params db.CreateEmailTokenParams) (*db.EmailToken, error) {
+	return db.New().CreateEmailToken(ctx, markblogdb.Stdlib(), params)
+}
+
+// ConsumeEmailToken deletes and returns an unexpired token, so that each
+// one can be used at most once.
+//
+//encore:api private method=POST path=/api/email-token/consume
+func ConsumeEmailToken(ctx context.Context, params db.ConsumeEmailTokenParams) (*db.EmailToken, error) {
+	return db.New().ConsumeEmailToken(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=POST path=/api/email-token/delete-for-user
+func DeleteEmailTokensForUser(ctx context.Context, params db.DeleteEmailTokensForUserParams) error {
+	return db.New().DeleteEmailTokensForUser(ctx, markblogdb.Stdlib(), params)
+}
+
+var _ = cron.NewJob("delete-expired-email-tokens", cron.JobConfig{
+	Title:    "Delete expired email tokens",
+	Every:    24 * cron.Hour,
+	Endpoint: DeleteExpiredEmailTokens,
+})
+
+type DeleteEmailTokensResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/email-token/delete-expired
+func DeleteExpiredEmailTokens(ctx context.Context) (*DeleteEmailTokensResult, error) {
+	res := new(DeleteEmailTokensResult)
+	var err error
+	res.Deleted, err = db.New().DeleteExpiredEmailTokens(ctx, markblogdb.Stdlib())
+	return res, err
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/beta/errs"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+)
+
+type GetSiteSettingResult struct {
+	Value string `json:"value"`
+}
+
+//encore:api private method=GET path=/api/setting/:key
+func GetSiteSetting(ctx context.Context, key string) (*GetSiteSettingResult, error) {
+	value, err := db.New().GetSiteSetting(ctx, markblogdb.Stdlib(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &GetSiteSettingResult{Value: value}, nil
+}
+
+//encore:api private method=POST path=/api/setting
+func UpsertSiteSetting(ctx context.Context, params db.UpsertSiteSettingParams) error {
+	return db.New().UpsertSiteSetting(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=POST path=/api/invite
+func CreateInvite(ctx context.Context, params db.CreateInviteParams) (*db.Invite, error) {
+	return db.New().CreateInvite(ctx, markblogdb.Stdlib(), params)
+}
+
+type GetInvitesCreatedByResult struct {
+	Invites []db.Invite `json:"invites"`
+}
+
+//encore:api private method=GET path=/api/invite/created-by/:userID
+func GetInvitesCreatedBy(ctx context.Context, userID uuid.UUID) (*GetInvitesCreatedByResult, error) {
+	rows, err := db.New().GetInvitesCreatedBy(ctx, markblogdb.Stdlib(), &userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetInvitesCreatedByResult{
+		Invites: make([]db.Invite, 0),
+	}
+	for _, r := range rows {
+		res.Invites = append(res.Invites, *r)
+	}
+
+	return res, nil
+}
+
+type CountInviteUsesCreatedByResult struct {
+	Uses int64 `json:"uses"`
+}
+
+// CountInviteUsesCreatedBy returns how much of the user's invite quota is
+// spent. Invites that can still be redeemed count with all their uses,
+// expired and revoked ones only with the uses they actually had.
+//
+//encore:api private method=GET path=/api/invite/uses/:userID
+func CountInviteUsesCreatedBy(ctx context.Context, userID uuid.UUID) (*CountInviteUsesCreatedByResult, error) {
+	res := new(CountInviteUsesCreatedByResult)
+	var err error
+	res.Uses, err = db.New().CountInviteUsesCreatedBy(ctx, markblogdb.Stdlib(), &userID)
+	return res, err
+}
+
+type CreateInviteWithinQuotaParams struct {
+	Code      string
+	CreatedBy uuid.UUID
+	MaxUses   int32
+	ExpiresAt time.Time
+}
+
+// CreateInviteWithinQuota creates an invite unless its uses would take the
+// creator over their quota, as counted by CountInviteUsesCreatedBy. The
+// quota is locked while counting, so that concurrent requests cannot both
+// squeeze in. It fails with ResourceExhausted if the quota does not allow
+// the invite.
+//
+//encore:api private method=POST path=/api/invite/within-quota
+func CreateInviteWithinQuota(ctx context.Context, params CreateInviteWithinQuotaParams) (*db.Invite, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	quota, err := q.GetInviteQuotaForUpdate(ctx, tx, params.CreatedBy)
+	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+		return nil, err
+	}
+
+	uses, err := q.CountInviteUsesCreatedBy(ctx, tx, &params.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if uses+int64(params.MaxUses) > int64(quota) {
+		return nil, &errs.Error{Code: errs.ResourceExhausted, Message: "invite quota exhausted"}
+	}
+
+	invite, err := q.CreateInvite(ctx, tx, db.CreateInviteParams{
+		Code:      params.Code,
+		CreatedBy: &params.CreatedBy,
+		MaxUses:   params.MaxUses,
+		ExpiresAt: params.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+type RevokeInviteResult struct {
+	Revoked bool `json:"revoked"`
+}
+
+//encore:api private method=POST path=/api/invite/revoke
+func RevokeInvite(ctx context.Context, params db.RevokeInviteParams) (*RevokeInviteResult, error) {
+	n, err := db.New().RevokeInvite(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &RevokeInviteResult{Revoked: n > 0}, nil
+}
+
+type GetInviteRedemptionsResult struct {
+	Redemptions []db.GetInviteRedemptionsRow `json:"redemptions"`
+}
+
+//encore:api private method=GET path=/api/invite/redemptions
+func GetInviteRedemptions(ctx context.Context, params db.GetInviteRedemptionsParams) (*GetInviteRedemptionsResult, error) {
+	rows, err := db.New().GetInviteRedemptions(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetInviteRedemptionsResult{
+		Redemptions: make([]db.GetInviteRedemptionsRow, 0),
+	}
+	for _, r := range rows {
+		res.Redemptions = append(res.Redemptions, *r)
+	}
+
+	return res, nil
+}
+
+type GetInviteQuotaResult struct {
+	Quota int32 `json:"quota"`
+}
+
+// GetInviteQuota returns how many invites the user may create. Users
+// without a quota get zero.
+//
+//encore:api private method=GET path=/api/invite/quota/:userID
+func GetInviteQuota(ctx context.Context, userID uuid.UUID) (*GetInviteQuotaResult, error) {
+	quota, err := db.New().GetInviteQuota(ctx, markblogdb.Stdlib(), userID)
+	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+		return nil, err
+	}
+	return &GetInviteQuotaResult{Quota: quota}, nil
+}
+
+//encore:api private method=POST path=/api/invite/quota
+func SetInviteQuota(ctx context.Context, params db.SetInviteQuotaParams) error {
+	return db.New().SetInviteQuota(ctx, markblogdb.Stdlib(), params)
+}
+
+type CreateInvitedUserParams struct {
+	Username     string
+	PasswordHash string
+	InviteCode   string
+}
+
+// CreateInvitedUser creates a user and spends one use of their invite in a
+// single transaction, so that an invite is never used up by a failed
+// registration. It fails with FailedPrecondition if the invite does not
+// exist, has expired or is used up.
+//
+//encore:api private method=POST path=/api/user/invited
+func CreateInvitedUser(ctx context.Context, params CreateInvitedUserParams) (*db.User, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	invite, err := q.RedeemInvite(ctx, tx, params.InviteCode)
+	if err != nil {
+		if errors.Is(err, sqldb.ErrNoRows) {
+			return nil, &errs.Error{Code: errs.FailedPrecondition, Message: "invalid invite"}
+		}
+		return nil, err
+	}
+
+	user, err := q.CreateUser(ctx, tx, db.CreateUserParams{
+		Username:     params.Username,
+		PasswordHash: params.PasswordHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.CreateInviteRedemption(ctx, tx, db.CreateInviteRedemptionParams{
+		UserID:    user.ID,
+		InviteID:  invite.ID,
+		InvitedBy: invite.CreatedBy,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
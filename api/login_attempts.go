@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+)
+
+//encore:api private method=POST path=/api/login-attempt/failure
+func RecordLoginFailure(ctx context.Context, params db.RecordLoginFailureParams) (*db.LoginAttempt, error) {
+	return db.New().RecordLoginFailure(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=POST path=/api/login-attempt/lock
+func SetLoginLockedUntil(ctx context.Context, params db.SetLoginLockedUntilParams) error {
+	return db.New().SetLoginLockedUntil(ctx, markblogdb.Stdlib(), params)
+}
+
+type LoginAttemptsResult struct {
+	Attempts []db.LoginAttempt `json:"attempts"`
+}
+
+//encore:api private method=GET path=/api/login-attempt/active
+func GetActiveLoginLocks(ctx context.Context, params db.GetActiveLoginLocksParams) (*LoginAttemptsResult, error) {
+	rows, err := db.New().GetActiveLoginLocks(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &LoginAttemptsResult{
+		Attempts: make([]db.LoginAttempt, 0),
+	}
+	for _, r := range rows {
+		res.Attempts = append(res.Attempts, *r)
+	}
+
+	return res, nil
+}
+
+// GetLockedLoginAttempts lists every username and IP address that is
+// currently locked out of logging in.
+//
+//encore:api private method=GET path=/api/login-attempt/locked
+func GetLockedLoginAttempts(ctx context.Context, params db.GetLockedLoginAttemptsParams) (*LoginAttemptsResult, error) {
+	rows, err := db.New().GetLockedLoginAttempts(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &LoginAttemptsResult{
+		Attempts: make([]db.LoginAttempt, 0),
+	}
+	for _, r := range rows {
+		res.Attempts = append(res.Attempts, *r)
+	}
+
+	return res, nil
+}
+
+type ClearLoginAttemptsResult struct {
+	Cleared bool `json:"cleared"`
+}
+
+// ClearLoginAttempts forgets the failed attempts recorded for a username or
+// IP address, lifting any lockout on it.
+//
+//encore:api private method=POST path=/api/login-attempt/clear
+func ClearLoginAttempts(ctx context.Context, params db.ClearLoginAttemptsParams) (*ClearLoginAttemptsResult, error) {
+	n, err := db.New().ClearLoginAttempts(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &ClearLoginAttemptsResult{Cleared: n > 0}, nil
+}
+
+var _ = cron.NewJob("delete-stale-login-attempts", cron.JobConfig{
+	Title:    "Forget old failed login attempts",
+	Every:    24 * cron.Hour,
+	Endpoint: DeleteStaleLoginAttempts,
+})
+
+type DeleteStaleLoginAttemptsResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/login-attempt/delete-stale
+func DeleteStaleLoginAttempts(ctx context.Context) (*DeleteStaleLoginAttemptsResult, error) {
+	res := new(DeleteStaleLoginAttemptsResult)
+	var err error
+	res.Deleted, err = db.New().DeleteStaleLoginAttempts(ctx, markblogdb.Stdlib())
+	return res, err
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/types/uuid"
+)
+
+type SetMentionsParams struct {
+	PostID uuid.UUID
+	// CommentID is set when the mentions are made in a comment on the post.
+	CommentID *uuid.UUID
+	UserIDs   []uuid.UUID
+}
+
+// SetMentions replaces the users mentioned by a post or comment.
+//
+//encore:api private method=POST path=/api/mentions
+func SetMentions(ctx context.Context, params SetMentionsParams) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	if err := q.DeleteMentionsForSource(ctx, tx, db.DeleteMentionsForSourceParams{
+		PostID:    params.PostID,
+		CommentID: params.CommentID,
+	}); err != nil {
+		return err
+	}
+
+	for _, userID := range params.UserIDs {
+		if err := q.CreateMention(ctx, tx, db.CreateMentionParams{
+			UserID:    userID,
+			PostID:    params.PostID,
+			CommentID: params.CommentID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type GetLatestMentionsForUserResult struct {
+	Mentions []db.GetLatestMentionsForUserRow `json:"mentions"`
+}
+
+//encore:api private method=POST path=/api/mentions/latest
+func GetLatestMentionsForUser(ctx context.Context, params db.GetLatestMentionsForUserParams) (*GetLatestMentionsForUserResult, error) {
+	rows, err := db.New().GetLatestMentionsForUser(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetLatestMentionsForUserResult{
+		Mentions: make([]db.GetLatestMentionsForUserRow, 0),
+	}
+	for _, r := range rows {
+		res.Mentions = append(res.Mentions, *r)
+	}
+
+	return res, nil
+}
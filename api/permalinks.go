@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+)
+
+//encore:api private method=POST path=/api/post/slug
+func GetPostBySlug(ctx context.Context, params db.GetPostBySlugParams) (*db.Post, error) {
+	return db.New().GetPostBySlug(ctx, markblogdb.Stdlib(), params)
+}
+
+type GetPostIDBySlugHistoryResult struct {
+	PostID uuid.UUID `json:"post_id"`
+}
+
+//encore:api private method=POST path=/api/post/slug/history
+func GetPostIDBySlugHistory(ctx context.Context, params db.GetPostIDBySlugHistoryParams) (*GetPostIDBySlugHistoryResult, error) {
+	res := new(GetPostIDBySlugHistoryResult)
+	var err error
+	res.PostID, err = db.New().GetPostIDBySlugHistory(ctx, markblogdb.Stdlib(), params)
+	return res, err
+}
+
+type IsPostSlugTakenResult struct {
+	Taken bool `json:"taken"`
+}
+
+//encore:api private method=POST path=/api/post/slug/taken
+func IsPostSlugTaken(ctx context.Context, params db.IsPostSlugTakenParams) (*IsPostSlugTakenResult, error) {
+	res := new(IsPostSlugTakenResult)
+	var err error
+	res.Taken, err = db.New().IsPostSlugTaken(ctx, markblogdb.Stdlib(), params)
+	return res, err
+}
+
+type ChangePostPermalinkParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Title  string
+	Slug   string
+}
+
+// ChangePostPermalink sets the title and slug of a post. The old slug keeps
+// redirecting to the post. It fails with NotFound if the post is deleted,
+// with PermissionDenied unless it belongs to UserID and with AlreadyExists
+// if the author uses the slug for another post.
+//
+//encore:api private method=POST path=/api/post/permalink
+func ChangePostPermalink(ctx context.Context, params ChangePostPermalinkParams) (*db.Post, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	post, err := q.GetPostByIDForUpdate(ctx, tx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.DeletedAt.After(time.Unix(0, 0)) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "post is deleted"}
+	}
+
+	if post.UserID != params.UserID {
+		return nil, &errs.Error{Code: errs.PermissionDenied, Message: "not the author of the post"}
+	}
+
+	if post.Slug != params.Slug {
+		taken, err := q.IsPostSlugTaken(ctx, tx, db.IsPostSlugTakenParams{
+			UserID: post.UserID,
+			Slug:   params.Slug,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, &errs.Error{Code: errs.AlreadyExists, Message: "slug is already in use"}
+		}
+
+		if err := q.CreatePostSlugRedirect(ctx, tx, db.CreatePostSlugRedirectParams{
+			UserID: post.UserID,
+			Slug:   post.Slug,
+			PostID: post.ID,
+		}); err != nil {
+			return nil, err
+		}
+
+		// Going back to an earlier slug makes it current again.
+		if err := q.DeletePostSlugRedirect(ctx, tx, db.DeletePostSlugRedirectParams{
+			UserID: post.UserID,
+			Slug:   params.Slug,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	post, err = q.UpdatePostPermalink(ctx, tx, db.UpdatePostPermalinkParams{
+		ID:    post.ID,
+		Title: params.Title,
+		Slug:  params.Slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+)
+
+type EditPostParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Content   string
+	Excerpt   string
+	WordCount int32
+}
+
+// EditPost replaces the content of a post, keeping the version it replaces
+// as a revision. It fails with NotFound if the post is deleted and with
+// PermissionDenied unless it belongs to UserID, and leaves the post
+// untouched if the content is unchanged.
+//
+//encore:api private method=POST path=/api/post/edit
+func EditPost(ctx context.Context, params EditPostParams) (*db.Post, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	post, err := q.GetPostByIDForUpdate(ctx, tx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.DeletedAt.After(time.Unix(0, 0)) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "post is deleted"}
+	}
+
+	if post.UserID != params.UserID {
+		return nil, &errs.Error{Code: errs.PermissionDenied, Message: "not the author of the post"}
+	}
+
+	if post.Content == params.Content {
+		return post, nil
+	}
+
+	if err := q.CreatePostRevision(ctx, tx, db.CreatePostRevisionParams{
+		PostID:    post.ID,
+		Content:   post.Content,
+		CreatedAt: post.UpdatedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	post, err = q.UpdatePostContent(ctx, tx, db.UpdatePostContentParams{
+		ID:        post.ID,
+		Content:   params.Content,
+		Excerpt:   params.Excerpt,
+		WordCount: params.WordCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+type GetPostRevisionsResult struct {
+	Revisions []db.PostRevision `json:"revisions"`
+}
+
+//encore:api private method=GET path=/api/post/revisions/:postID
+func GetPostRevisions(ctx context.Context, postID uuid.UUID) (*GetPostRevisionsResult, error) {
+	rows, err := db.New().GetPostRevisions(ctx, markblogdb.Stdlib(), postID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetPostRevisionsResult{
+		Revisions: make([]db.PostRevision, 0),
+	}
+	for _, r := range rows {
+		res.Revisions = append(res.Revisions, *r)
+	}
+
+	return res, nil
+}
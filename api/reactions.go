@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+)
+
+type ToggleReactionParams struct {
+	PostID uuid.UUID
+	// CommentID is set when the reaction is to a comment on the post.
+	CommentID *uuid.UUID
+	UserID    uuid.UUID
+	Emoji     string
+	// RemoveOnly is set for emoji that may no longer be added, so that
+	// reactions made with them can still be taken back.
+	RemoveOnly bool
+}
+
+type ToggleReactionResult struct {
+	// Reacted is whether the user now has the reaction.
+	Reacted bool `json:"reacted"`
+}
+
+// ToggleReaction adds the reaction if the user has not made it yet, and
+// takes it back otherwise. With RemoveOnly it fails with
+// FailedPrecondition instead of adding the reaction.
+//
+//encore:api private method=POST path=/api/reactions/toggle
+func ToggleReaction(ctx context.Context, params ToggleReactionParams) (*ToggleReactionResult, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	target := params.PostID
+	if params.CommentID != nil {
+		target = *params.CommentID
+	}
+
+	q := db.New()
+	deleted, err := q.DeleteReaction(ctx, tx, db.DeleteReactionParams{
+		TargetID: target,
+		UserID:   params.UserID,
+		Emoji:    params.Emoji,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted == 0 {
+		if params.RemoveOnly {
+			return nil, &errs.Error{Code: errs.FailedPrecondition, Message: "emoji is not allowed"}
+		}
+		if _, err := q.CreateReaction(ctx, tx, db.CreateReactionParams{
+			PostID:    params.PostID,
+			CommentID: params.CommentID,
+			UserID:    params.UserID,
+			Emoji:     params.Emoji,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &ToggleReactionResult{Reacted: deleted == 0}, nil
+}
+
+type GetReactionCountsResult struct {
+	Counts []db.GetReactionCountsRow `json:"counts"`
+}
+
+//encore:api private method=POST path=/api/reactions/counts
+func GetReactionCounts(ctx context.Context, params db.GetReactionCountsParams) (*GetReactionCountsResult, error) {
+	rows, err := db.New().GetReactionCounts(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetReactionCountsResult{
+		Counts: make([]db.GetReactionCountsRow, 0),
+	}
+	for _, r := range rows {
+		res.Counts = append(res.Counts, *r)
+	}
+
+	return res, nil
+}
+
+type GetReactionsForTargetResult struct {
+	Reactions []db.GetReactionsForTargetRow `json:"reactions"`
+}
+
+//encore:api private method=POST path=/api/reactions/list
+func GetReactionsForTarget(ctx context.Context, params db.GetReactionsForTargetParams) (*GetReactionsForTargetResult, error) {
+	rows, err := db.New().GetReactionsForTarget(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetReactionsForTargetResult{
+		Reactions: make([]db.GetReactionsForTargetRow, 0),
+	}
+	for _, r := range rows {
+		res.Reactions = append(res.Reactions, *r)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"encore.app/api/db"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+)
+
+var secrets struct {
+	// AdminUsername names an account that is granted the admin role when
+	// the service starts and no account holds it yet. Register the account
+	// before setting it, and leave it empty to skip the bootstrap.
+	AdminUsername string
+}
+
+//encore:service
+type Service struct{}
+
+func initService() (*Service, error) {
+	bootstrapAdmin(context.Background())
+	return &Service{}, nil
+}
+
+// bootstrapAdmin grants the admin role to the account named by the
+// AdminUsername secret, so that the first admin does not have to be
+// created by hand in the database. It only acts while nobody is an admin:
+// usernames can be registered by anyone, and once the first admin exists
+// further admins are granted through the app.
+func bootstrapAdmin(ctx context.Context) {
+	if secrets.AdminUsername == "" {
+		return
+	}
+
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		rlog.Error("bootstrap admin failed", "err", err)
+		return
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	admins, err := q.CountRoleHolders(ctx, tx, "admin")
+	if err != nil {
+		rlog.Error("bootstrap admin lookup failed", "err", err)
+		return
+	}
+	if admins > 0 {
+		rlog.Warn("skipping admin bootstrap, an admin already exists; the AdminUsername secret can be cleared")
+		return
+	}
+
+	user, err := q.GetUserByUsername(ctx, tx, secrets.AdminUsername)
+	if err != nil {
+		if errors.Is(err, sqldb.ErrNoRows) {
+			rlog.Warn("skipping admin bootstrap, the AdminUsername account does not exist")
+			return
+		}
+		rlog.Error("bootstrap admin lookup failed", "err", err)
+		return
+	}
+
+	if _, err := q.GrantUserRole(ctx, tx, db.GrantUserRoleParams{
+		UserID: user.ID,
+		Role:   "admin",
+	}); err != nil {
+		rlog.Error("bootstrap admin grant failed", "err", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		rlog.Error("bootstrap admin grant failed", "err", err)
+		return
+	}
+	rlog.Info("granted admin role to bootstrap admin", "username", user.Username)
+}
+
+type GetUserRolesResult struct {
+	Roles []string `json:"roles"`
+}
+
+//encore:api private method=GET path=/api/role/user/:userID
+func GetUserRoles(ctx context.Context, userID uuid.UUID) (*GetUserRolesResult, error) {
+	roles, err := db.New().GetUserRoles(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return &GetUserRolesResult{Roles: roles}, nil
+}
+
+type GetRoleAssignmentsResult struct {
+	Assignments []db.GetRoleAssignmentsRow `json:"assignments"`
+}
+
+//encore:api private method=GET path=/api/role
+func GetRoleAssignments(ctx context.Context) (*GetRoleAssignmentsResult, error) {
+	rows, err := db.New().GetRoleAssignments(ctx, markblogdb.Stdlib())
+	if err != nil {
+		return nil, err
+	}
+	res := &GetRoleAssignmentsResult{
+		Assignments: make([]db.GetRoleAssignmentsRow, 0),
+	}
+	for _, r := range rows {
+		res.Assignments = append(res.Assignments, *r)
+	}
+
+	return res, nil
+}
+
+type GrantUserRoleResult struct {
+	Granted bool `json:"granted"`
+}
+
+//encore:api private method=POST path=/api/role/grant
+func GrantUserRole(ctx context.Context, params db.GrantUserRoleParams) (*GrantUserRoleResult, error) {
+	n, err := db.New().GrantUserRole(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &GrantUserRoleResult{Granted: n > 0}, nil
+}
+
+type RevokeUserRoleResult struct {
+	Revoked bool `json:"revoked"`
+}
+
+//encore:api private method=POST path=/api/role/revoke
+func RevokeUserRole(ctx context.Context, params db.RevokeUserRoleParams) (*RevokeUserRoleResult, error) {
+	n, err := db.New().RevokeUserRole(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &RevokeUserRoleResult{Revoked: n > 0}, nil
+}
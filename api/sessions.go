@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+	"encore.dev/types/uuid"
+)
+
+//encore:api private method=POST path=/api/session
+func CreateSession(ctx context.Context, params db.CreateSessionParams) (*db.Session, error) {
+	return db.New().CreateSession(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=GET path=/api/session/id/:id
+func GetActiveSessionByID(ctx context.Context, id uuid.UUID) (*db.Session, error) {
+	return db.New().GetActiveSessionByID(ctx, markblogdb.Stdlib(), id)
+}
+
+type GetActiveSessionsForUserResult struct {
+	Sessions []db.Session `json:"sessions"`
+}
+
+//encore:api private method=GET path=/api/session/user/:userID
+func GetActiveSessionsForUser(ctx context.Context, userID uuid.UUID) (*GetActiveSessionsForUserResult, error) {
+	rows, err := db.New().GetActiveSessionsForUser(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetActiveSessionsForUserResult{
+		Sessions: make([]db.Session, 0),
+	}
+	for _, r := range rows {
+		res.Sessions = append(res.Sessions, *r)
+	}
+
+	return res, nil
+}
+
+//encore:api private method=POST path=/api/session/touch/:id
+func TouchSession(ctx context.Context, id uuid.UUID) error {
+	return db.New().TouchSession(ctx, markblogdb.Stdlib(), id)
+}
+
+//encore:api private method=POST path=/api/session/delete/:id
+func DeleteSession(ctx context.Context, id uuid.UUID) error {
+	return db.New().DeleteSession(ctx, markblogdb.Stdlib(), id)
+}
+
+type DeleteSessionsResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+//encore:api private method=POST path=/api/session/delete-for-user
+func DeleteSessionForUser(ctx context.Context, params db.DeleteSessionForUserParams) (*DeleteSessionsResult, error) {
+	res := new(DeleteSessionsResult)
+	var err error
+	res.Deleted, err = db.New().DeleteSessionForUser(ctx, markblogdb.Stdlib(), params)
+	return res, err
+}
+
+//encore:api private method=POST path=/api/session/delete-others
+func DeleteOtherSessionsForUser(ctx context.Context, params db.DeleteOtherSessionsForUserParams) (*DeleteSessionsResult, error) {
+	res := new(DeleteSessionsResult)
+	var err error
+	res.Deleted, err = db.New().DeleteOtherSessionsForUser(ctx, markblogdb.Stdlib(), params)
+	return res, err
+}
+
+//encore:api private method=POST path=/api/session/delete-all/:userID
+func DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) (*DeleteSessionsResult, error) {
+	res := new(DeleteSessionsResult)
+	var err error
+	res.Deleted, err = db.New().DeleteSessionsForUser(ctx, markblogdb.Stdlib(), userID)
+	return res, err
+}
+
+var _ = cron.NewJob("delete-expired-sessions", cron.JobConfig{
+	Title:    "Delete expired sessions",
+	Every:    1 * cron.Hour,
+	Endpoint: DeleteExpiredSessions,
+})
+
+//encore:api private method=POST path=/api/session/delete-expired
+func DeleteExpiredSessions(ctx context.Context) (*DeleteSessionsResult, error) {
+	res := new(DeleteSessionsResult)
+	var err error
+	res.Deleted, err = db.New().DeleteExpiredSessions(ctx, markblogdb.Stdlib())
+	return res, err
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+
+	"encore.app/api/db"
+	"encore.dev/types/uuid"
+)
+
+type SetPostTagsParams struct {
+	PostID uuid.UUID
+	Tags   []string
+}
+
+// SetPostTags replaces the tags of a post, creating tags that do not exist
+// yet.
+//
+//encore:api private method=POST path=/api/post/tags
+func SetPostTags(ctx context.Context, params SetPostTagsParams) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	if err := q.DeletePostTags(ctx, tx, params.PostID); err != nil {
+		return err
+	}
+
+	for _, name := range params.Tags {
+		tagID, err := q.UpsertTag(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if err := q.CreatePostTag(ctx, tx, db.CreatePostTagParams{
+			PostID: params.PostID,
+			TagID:  tagID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type GetPostTagsResult struct {
+	Tags []string `json:"tags"`
+}
+
+//encore:api private method=GET path=/api/post/tags/:postID
+func GetPostTags(ctx context.Context, postID uuid.UUID) (*GetPostTagsResult, error) {
+	tags, err := db.New().GetPostTags(ctx, markblogdb.Stdlib(), postID)
+	if err != nil {
+		return nil, err
+	}
+	return &GetPostTagsResult{Tags: tags}, nil
+}
+
+type GetLatestPostsByTagResult struct {
+	Posts []db.GetLatestPostsByTagRow `json:"posts"`
+}
+
+//encore:api private method=POST path=/api/posts/tag
+func GetLatestPostsByTag(ctx context.Context, params db.GetLatestPostsByTagParams) (*GetLatestPostsByTagResult, error) {
+	rows, err := db.New().GetLatestPostsByTag(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetLatestPostsByTagResult{
+		Posts: make([]db.GetLatestPostsByTagRow, 0),
+	}
+	for _, r := range rows {
+		res.Posts = append(res.Posts, *r)
+	}
+
+	return res, nil
+}
+
+type GetTrendingTagsResult struct {
+	Tags []db.GetTrendingTagsRow `json:"tags"`
+}
+
+//encore:api private method=POST path=/api/tags/trending
+func GetTrendingTags(ctx context.Context, params db.GetTrendingTagsParams) (*GetTrendingTagsResult, error) {
+	rows, err := db.New().GetTrendingTags(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetTrendingTagsResult{
+		Tags: make([]db.GetTrendingTagsRow, 0),
+	}
+	for _, r := range rows {
+		res.Tags = append(res.Tags, *r)
+	}
+
+	return res, nil
+}
+
+type SearchTagsByPrefixResult struct {
+	Tags []db.SearchTagsByPrefixRow `json:"tags"`
+}
+
+//encore:api private method=POST path=/api/tags/search
+func SearchTagsByPrefix(ctx context.Context, params db.SearchTagsByPrefixParams) (*SearchTagsByPrefixResult, error) {
+	rows, err := db.New().SearchTagsByPrefix(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	res := &SearchTagsByPrefixResult{
+		Tags: make([]db.SearchTagsByPrefixRow, 0),
+	}
+	for _, r := range rows {
+		res.Tags = append(res.Tags, *r)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+)
+
+// trashRetentionKey is the site setting holding how many days deleted posts
+// and comments are kept before being purged. defaultTrashRetentionDays
+// applies while it is unset.
+const (
+	trashRetentionKey         = "trash_retention_days"
+	defaultTrashRetentionDays = 30
+)
+
+type SoftDeleteResult struct {
+	Deleted bool `json:"deleted"`
+}
+
+type RestoreResult struct {
+	Restored bool `json:"restored"`
+}
+
+//encore:api private method=POST path=/api/post/delete
+func SoftDeletePost(ctx context.Context, params db.SoftDeletePostParams) (*SoftDeleteResult, error) {
+	n, err := db.New().SoftDeletePost(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &SoftDeleteResult{Deleted: n > 0}, nil
+}
+
+//encore:api private method=POST path=/api/post/restore/:id
+func RestorePost(ctx context.Context, id uuid.UUID) (*RestoreResult, error) {
+	n, err := db.New().RestorePost(ctx, markblogdb.Stdlib(), id)
+	if err != nil {
+		return nil, err
+	}
+	return &RestoreResult{Restored: n > 0}, nil
+}
+
+type GetDeletedPostsByUserResult struct {
+	Posts []db.Post `json:"posts"`
+}
+
+//encore:api private method=GET path=/api/post/deleted/:userID
+func GetDeletedPostsByUser(ctx context.Context, userID uuid.UUID) (*GetDeletedPostsByUserResult, error) {
+	rows, err := db.New().GetDeletedPostsByUser(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetDeletedPostsByUserResult{
+		Posts: make([]db.Post, 0),
+	}
+	for _, r := range rows {
+		res.Posts = append(res.Posts, *r)
+	}
+
+	return res, nil
+}
+
+//encore:api private method=POST path=/api/comment/delete
+func SoftDeleteComment(ctx context.Context, params db.SoftDeleteCommentParams) (*SoftDeleteResult, error) {
+	n, err := db.New().SoftDeleteComment(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &SoftDeleteResult{Deleted: n > 0}, nil
+}
+
+//encore:api private method=POST path=/api/comment/restore/:id
+func RestoreComment(ctx context.Context, id uuid.UUID) (*RestoreResult, error) {
+	n, err := db.New().RestoreComment(ctx, markblogdb.Stdlib(), id)
+	if err != nil {
+		return nil, err
+	}
+	return &RestoreResult{Restored: n > 0}, nil
+}
+
+type GetDeletedCommentsByUserResult struct {
+	Comments []db.Comment `json:"comments"`
+}
+
+//encore:api private method=GET path=/api/comment/deleted/:userID
+func GetDeletedCommentsByUser(ctx context.Context, userID uuid.UUID) (*GetDeletedCommentsByUserResult, error) {
+	rows, err := db.New().GetDeletedCommentsByUser(ctx, markblogdb.Stdlib(), &userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetDeletedCommentsByUserResult{
+		Comments: make([]db.Comment, 0),
+	}
+	for _, r := range rows {
+		res.Comments = append(res.Comments, *r)
+	}
+
+	return res, nil
+}
+
+var _ = cron.NewJob("purge-deleted-content", cron.JobConfig{
+	Title:    "Purge posts and comments deleted past the retention period",
+	Every:    24 * cron.Hour,
+	Endpoint: PurgeDeletedContent,
+})
+
+type PurgeDeletedContentResult struct {
+	Posts    int64 `json:"posts"`
+	Comments int64 `json:"comments"`
+}
+
+//encore:api private method=POST path=/api/trash/purge
+func PurgeDeletedContent(ctx context.Context) (*PurgeDeletedContentResult, error) {
+	q := db.New()
+
+	days := defaultTrashRetentionDays
+	value, err := q.GetSiteSetting(ctx, markblogdb.Stdlib(), trashRetentionKey)
+	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			days = n
+		} else {
+			rlog.Warn("ignoring invalid trash retention setting", "value", value)
+		}
+	}
+	before := time.Now().AddDate(0, 0, -days)
+
+	res := new(PurgeDeletedContentResult)
+	if res.Comments, err = q.PurgeDeletedComments(ctx, markblogdb.Stdlib(), before); err != nil {
+		return nil, err
+	}
+	res.Posts, err = q.PurgeDeletedPosts(ctx, markblogdb.Stdlib(), before)
+	return res, err
+}
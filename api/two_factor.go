@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"encore.app/api/db"
+	"encore.dev/types/uuid"
+)
+
+//encore:api private method=POST path=/api/totp
+func UpsertUserTOTP(ctx context.Context, params db.UpsertUserTOTPParams) (*db.UserTotp, error) {
+	return db.New().UpsertUserTOTP(ctx, markblogdb.Stdlib(), params)
+}
+
+//encore:api private method=GET path=/api/totp/user/:userID
+func GetUserTOTP(ctx context.Context, userID uuid.UUID) (*db.UserTotp, error) {
+	return db.New().GetUserTOTP(ctx, markblogdb.Stdlib(), userID)
+}
+
+type EnableUserTOTPParams struct {
+	UserID             uuid.UUID
+	LastUsedStep       int64
+	RecoveryCodeHashes []string
+}
+
+//encore:api private method=POST path=/api/totp/enable
+func EnableUserTOTP(ctx context.Context, params EnableUserTOTPParams) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.New().EnableUserTOTP(ctx, tx, db.EnableUserTOTPParams{
+		UserID:       params.UserID,
+		LastUsedStep: params.LastUsedStep,
+	}); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, params.UserID, params.RecoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type UseUserTOTPStepResult struct {
+	Accepted bool `json:"accepted"`
+}
+
+//encore:api private method=POST path=/api/totp/use-step
+func UseUserTOTPStep(ctx context.Context, params db.UseUserTOTPStepParams) (*UseUserTOTPStepResult, error) {
+	n, err := db.New().UseUserTOTPStep(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &UseUserTOTPStepResult{Accepted: n > 0}, nil
+}
+
+//encore:api private method=POST path=/api/totp/disable/:userID
+func DisableUserTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	if err := q.DeleteUserTOTP(ctx, tx, userID); err != nil {
+		return err
+	}
+	if err := q.DeleteRecoveryCodesForUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type ReplaceRecoveryCodesParams struct {
+	UserID     uuid.UUID
+	CodeHashes []string
+}
+
+//encore:api private method=POST path=/api/recovery-codes
+func ReplaceRecoveryCodes(ctx context.Context, params ReplaceRecoveryCodesParams) error {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, params.UserID, params.CodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type ConsumeRecoveryCodeResult struct {
+	Consumed bool `json:"consumed"`
+}
+
+//encore:api private method=POST path=/api/recovery-codes/consume
+func ConsumeRecoveryCode(ctx context.Context, params db.ConsumeRecoveryCodeParams) (*ConsumeRecoveryCodeResult, error) {
+	n, err := db.New().ConsumeRecoveryCode(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsumeRecoveryCodeResult{Consumed: n > 0}, nil
+}
+
+// replaceRecoveryCodes swaps every recovery code of the user for the given
+// hashes within tx.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, hashes []string) error {
+	q := db.New()
+	if err := q.DeleteRecoveryCodesForUser(ctx, tx, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if err := q.CreateRecoveryCode(ctx, tx, db.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: h,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
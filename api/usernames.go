@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.app/api/db"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+)
+
+type GetUsernameHistoryResult struct {
+	History []db.UsernameHistory `json:"history"`
+}
+
+//encore:api private method=GET path=/api/user/username-history/:userID
+func GetUsernameHistory(ctx context.Context, userID uuid.UUID) (*GetUsernameHistoryResult, error) {
+	rows, err := db.New().GetUsernameHistory(ctx, markblogdb.Stdlib(), userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetUsernameHistoryResult{
+		History: make([]db.UsernameHistory, 0),
+	}
+	for _, r := range rows {
+		res.History = append(res.History, *r)
+	}
+
+	return res, nil
+}
+
+type GetLastUsernameChangeResult struct {
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// GetLastUsernameChange returns when the user last changed their username.
+// ChangedAt is zero for users who never have.
+//
+//encore:api private method=GET path=/api/user/username-history/:userID/last
+func GetLastUsernameChange(ctx context.Context, userID uuid.UUID) (*GetLastUsernameChangeResult, error) {
+	changedAt, err := db.New().GetLastUsernameChange(ctx, markblogdb.Stdlib(), userID)
+	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+		return nil, err
+	}
+	return &GetLastUsernameChangeResult{ChangedAt: changedAt}, nil
+}
+
+type GetPreviousUsernameOwnerResult struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+//encore:api private method=GET path=/api/user/previous-username/owner
+func GetPreviousUsernameOwner(ctx context.Context, params db.GetPreviousUsernameOwnerParams) (*GetPreviousUsernameOwnerResult, error) {
+	userID, err := db.New().GetPreviousUsernameOwner(ctx, markblogdb.Stdlib(), params)
+	if err != nil {
+		return nil, err
+	}
+	return &GetPreviousUsernameOwnerResult{UserID: userID}, nil
+}
+
+// ChangeUsername renames a user and records the old username in their
+// history in a single transaction.
+//
+//encore:api private method=POST path=/api/user/username
+func ChangeUsername(ctx context.Context, params db.UpdateUsernameParams) (*db.User, error) {
+	tx, err := markblogdb.Stdlib().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New()
+	user, err := q.GetUserByID(ctx, tx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.UpdateUsername(ctx, tx, params); err != nil {
+		return nil, err
+	}
+
+	if err := q.CreateUsernameChange(ctx, tx, db.CreateUsernameChangeParams{
+		UserID:      user.ID,
+		OldUsername: user.Username,
+		NewUsername: params.Username,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	user.Username = params.Username
+	return user, nil
+}
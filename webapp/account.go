@@ -0,0 +1,261 @@
+package webapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// accountDeletionGracePeriod is how long a requested account deletion can
+// still be cancelled before the background job carries it out.
+const accountDeletionGracePeriod = 14 * 24 * time.Hour
+
+//encore:api auth raw path=/app/auth/password
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, `{"error":"Current and new password are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.NewPassword) < 8 {
+		http.Error(w, `{"error":"Password must be at least 8 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if ok, _ := verifyPassword(r.Context(), user.PasswordHash, req.CurrentPassword); !ok {
+		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	hashedPassword, err := hashPassword(r.Context(), req.NewPassword)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to secure password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := api.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hashedPassword,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, auditPasswordChange, &user.ID, user.Username, auditSuccess, "")
+
+	// Whoever knew the old password may still hold a session elsewhere, so
+	// only the session that just proved knowledge of it survives.
+	res, err := api.DeleteOtherSessionsForUser(r.Context(), db.DeleteOtherSessionsForUserParams{
+		UserID: user.ID,
+		ID:     *current.SessionID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"revoked": res.Deleted,
+	})
+}
+
+//encore:api auth raw path=/app/auth/delete-account
+func DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		KeepComments bool   `json:"keep_comments"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, `{"error":"Password is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if ok, _ := verifyPassword(r.Context(), user.PasswordHash, req.Password); !ok {
+		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	deletion, err := api.ScheduleAccountDeletion(r.Context(), db.ScheduleAccountDeletionParams{
+		UserID:       user.ID,
+		KeepComments: req.KeepComments,
+		ScheduledFor: time.Now().Add(accountDeletionGracePeriod),
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"keep_comments": deletion.KeepComments,
+		"scheduled_for": deletion.ScheduledFor,
+	})
+}
+
+//encore:api auth raw path=/app/auth/delete-account/status
+func DeleteAccountStatus(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	deletion, err := api.GetAccountDeletionForUser(r.Context(), current.UserID)
+	if err != nil {
+		if isNotFound(err) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"scheduled": false,
+			})
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scheduled":     true,
+		"keep_comments": deletion.KeepComments,
+		"requested_at":  deletion.RequestedAt,
+		"scheduled_for": deletion.ScheduledFor,
+	})
+}
+
+//encore:api auth raw path=/app/auth/delete-account/cancel
+func CancelDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.CancelAccountDeletion(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !res.Cancelled {
+		http.Error(w, `{"error":"No deletion is scheduled"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,165 @@
+package webapp
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"encore.dev/types/uuid"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+
+	"encore.app/api"
+)
+
+// Post kinds. Notes are shown in full in the feed, articles by their
+// excerpt with the body fetched separately.
+const (
+	postNote    = "note"
+	postArticle = "article"
+)
+
+// Content limits, counted in characters rather than bytes so that
+// non-Latin text gets the same room.
+const (
+	maxNoteLength    = 300
+	maxArticleLength = 50_000
+	maxCommentLength = 128
+	maxExcerptLength = 280
+)
+
+// wordsPerMinute is the reading speed reading times are estimated at.
+const wordsPerMinute = 200
+
+// checkContent makes sure content is neither empty nor longer than posts of
+// the kind allow. On rejection it writes the error and returns false.
+func checkContent(w http.ResponseWriter, kind, content string) bool {
+	limit := maxNoteLength
+	if kind == postArticle {
+		limit = maxArticleLength
+	}
+	if content == "" || utf8.RuneCountInString(content) > limit {
+		http.Error(w, `{"error":"Content length is invalid"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// plainText returns the words a reader sees in Markdown content, without
+// markup, code blocks or image descriptions.
+func plainText(content string) string {
+	source := []byte(content)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var b strings.Builder
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				b.Write(n.Segment.Value(source))
+				if n.SoftLineBreak() || n.HardLineBreak() {
+					b.WriteByte(' ')
+				}
+			}
+		case *ast.AutoLink:
+			if entering {
+				b.Write(n.Label(source))
+			}
+		default:
+			if !entering && n.Type() == ast.TypeBlock {
+				b.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// summarize returns the excerpt and word count of a post's content. The
+// excerpt is the opening of its plain text, cut at a word boundary.
+func summarize(content string) (string, int32) {
+	plain := plainText(content)
+	words := int32(len(strings.Fields(plain)))
+
+	if utf8.RuneCountInString(plain) <= maxExcerptLength {
+		return plain, words
+	}
+	runes := []rune(plain)[:maxExcerptLength]
+	excerpt := string(runes)
+	if i := strings.LastIndexByte(excerpt, ' '); i > 0 {
+		excerpt = excerpt[:i]
+	}
+	return strings.TrimRight(excerpt, " ,;:.") + "…", words
+}
+
+// readingTime estimates how many minutes reading words takes, rounding up.
+func readingTime(words int32) int32 {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// excerptHTML renders an excerpt, which is plain text, as a paragraph.
+func excerptHTML(excerpt string) string {
+	return "<p>" + html.EscapeString(excerpt) + "</p>"
+}
+
+//encore:api public raw path=/app/post/body
+func PostBody(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		PostID string `json:"post_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	post, err := api.GetPostByID(r.Context(), postID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if isDeleted(post.DeletedAt) || !isVisible(post) {
+		http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           post.ID,
+		"kind":         post.Kind,
+		"title":        post.Title,
+		"content":      post.Content,
+		"html":         renderMarkdown(post.Content),
+		"excerpt":      post.Excerpt,
+		"word_count":   post.WordCount,
+		"reading_time": readingTime(post.WordCount),
+	})
+}
@@ -0,0 +1,626 @@
+package webapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"encore.dev/storage/objects"
+	"encore.dev/types/uuid"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"golang.org/x/image/draw"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+const (
+	maxAttachmentSize  = 5 << 20
+	maxImagePixels     = 25_000_000
+	maxPostAttachments = 10
+	maxFilenameLength  = 255
+	// thumbnailSize is the longest side of a thumbnail, in pixels.
+	thumbnailSize = 320
+)
+
+// attachmentTypes are the content types that can be uploaded, as sniffed
+// from the file rather than as claimed by the client.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":                true,
+	"image/png":                 true,
+	"image/gif":                 true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+var (
+	errImageTooLarge = errors.New("image dimensions are too large")
+	errMalformedGIF  = errors.New("malformed GIF")
+
+	attachmentRefPattern = regexp.MustCompile(`attachment:([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+)
+
+// attachmentInfo describes an attachment to clients.
+type attachmentInfo struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+}
+
+// thumbnailKey names the thumbnail object of an attachment. The api
+// service, which purges attachments, names it the same way.
+func thumbnailKey(id uuid.UUID) string {
+	return id.String() + "-thumb"
+}
+
+// withURLs fills in where the attachment can be downloaded. Without a
+// thumbnail, the thumbnail URL is the attachment itself.
+func (a attachmentInfo) withURLs() attachmentInfo {
+	a.URL = api.AttachmentBucket.PublicURL(a.ID.String()).String()
+	a.ThumbnailURL = a.URL
+	if a.HasThumbnail {
+		a.ThumbnailURL = api.AttachmentBucket.PublicURL(thumbnailKey(a.ID)).String()
+	}
+	return a
+}
+
+func newAttachmentInfo(a *db.Attachment) attachmentInfo {
+	return attachmentInfo{
+		ID:           a.ID,
+		Filename:     a.Filename,
+		ContentType:  a.ContentType,
+		Size:         a.Size,
+		Width:        a.Width,
+		Height:       a.Height,
+		HasThumbnail: a.HasThumbnail,
+	}.withURLs()
+}
+
+// feedAttachments decodes the attachment list the feed queries return.
+func feedAttachments(raw json.RawMessage) []attachmentInfo {
+	var list []attachmentInfo
+	if err := json.Unmarshal(raw, &list); err != nil {
+		println("Attachment list error:", err.Error())
+	}
+	for i := range list {
+		list[i] = list[i].withURLs()
+	}
+	if list == nil {
+		list = make([]attachmentInfo, 0)
+	}
+	return list
+}
+
+// extractAttachments returns the distinct attachments referenced as
+// attachment:ID in content, in the order they first appear.
+func extractAttachments(content string) []uuid.UUID {
+	content = codePattern.ReplaceAllString(content, " ")
+
+	ids := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range attachmentRefPattern.FindAllStringSubmatch(content, -1) {
+		id, err := uuid.FromString(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// checkAttachments returns the attachments content embeds, making sure they
+// were all uploaded by userID. On rejection it writes the error and returns
+// false.
+func checkAttachments(w http.ResponseWriter, ctx context.Context, userID uuid.UUID, content string) ([]uuid.UUID, bool) {
+	ids := extractAttachments(content)
+	if len(ids) > maxPostAttachments {
+		http.Error(w, `{"error":"Too many attachments"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	for _, id := range ids {
+		a, err := api.GetAttachmentByID(ctx, id)
+		if err != nil && !isNotFound(err) {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return nil, false
+		}
+		if err != nil || a.UserID == nil || *a.UserID != userID {
+			http.Error(w, `{"error":"Unknown attachment"}`, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+// attachPost stores which attachments a post embeds. The post itself is
+// already saved, so failures are only logged.
+func attachPost(ctx context.Context, postID uuid.UUID, ids []uuid.UUID) {
+	if err := api.SetPostAttachments(ctx, api.SetPostAttachmentsParams{
+		PostID:        postID,
+		AttachmentIDs: ids,
+	}); err != nil {
+		println("Attachment error:", err.Error())
+	}
+}
+
+// cleanFilename keeps the base name of an uploaded file, without control
+// characters and cut to maxFilenameLength bytes.
+func cleanFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if strings.TrimSpace(name) == "" {
+		return "file"
+	}
+	return name
+}
+
+// processedImage is an uploaded image re-encoded without its metadata.
+type processedImage struct {
+	data          []byte
+	width         int
+	height        int
+	thumbnail     []byte
+	thumbnailType string
+}
+
+// processImage decodes an image and encodes it again, which drops EXIF and
+// any other metadata. JPEGs are turned upright first, since the orientation
+// is lost with the metadata. Images larger than a thumbnail get one.
+func processImage(data []byte, contentType string) (*processedImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, errImageTooLarge
+	}
+
+	var img image.Image
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		if img, err = jpeg.Decode(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		if img, err = png.Decode(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		// The logical screen checked above says nothing about how many
+		// frames there are, each of which is decoded in full, so the
+		// pixel budget covers all of them together.
+		var pixels int
+		if pixels, err = gifPixels(data); err != nil {
+			return nil, err
+		}
+		if pixels > maxImagePixels {
+			return nil, errImageTooLarge
+		}
+		var g *gif.GIF
+		if g, err = gif.DecodeAll(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		img = g.Image[0]
+		err = gif.EncodeAll(&buf, g)
+	default:
+		return nil, errors.New("not an image: " + contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	res := &processedImage{data: buf.Bytes(), width: b.Dx(), height: b.Dy()}
+	if b.Dx() <= thumbnailSize && b.Dy() <= thumbnailSize {
+		return res, nil
+	}
+
+	w, h := thumbnailSize, b.Dy()*thumbnailSize/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*thumbnailSize/b.Dy(), thumbnailSize
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, b, draw.Src, nil)
+
+	var tbuf bytes.Buffer
+	if contentType == "image/jpeg" {
+		res.thumbnailType = "image/jpeg"
+		err = jpeg.Encode(&tbuf, thumb, &jpeg.Options{Quality: 80})
+	} else {
+		res.thumbnailType = "image/png"
+		err = png.Encode(&tbuf, thumb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.thumbnail = tbuf.Bytes()
+	return res, nil
+}
+
+// gifPixels adds up the areas of the frames in a GIF without decoding
+// them, by walking the blocks of the file.
+func gifPixels(data []byte) (int, error) {
+	if len(data) < 13 {
+		return 0, errMalformedGIF
+	}
+	i := 13
+	// A global color table follows the logical screen descriptor.
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks moves past a sequence of data sub-blocks, which ends
+	// with an empty one.
+	skipSubBlocks := func() bool {
+		for i < len(data) {
+			n := int(data[i])
+			i += 1 + n
+			if n == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	pixels := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension
+			i += 2
+			if !skipSubBlocks() {
+				return 0, errMalformedGIF
+			}
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return 0, errMalformedGIF
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			pixels += w * h
+			if pixels > maxImagePixels {
+				return pixels, nil
+			}
+			packed := data[i+9]
+			i += 10
+			if packed&0x80 != 0 {
+				i += 3 << (packed&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data.
+			i++
+			if !skipSubBlocks() {
+				return 0, errMalformedGIF
+			}
+		case 0x3B: // trailer
+			return pixels, nil
+		default:
+			return 0, errMalformedGIF
+		}
+	}
+	return 0, errMalformedGIF
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, from 1 (upright)
+// to 8. It returns 1 if there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF
+// structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// orient turns an image with the given EXIF orientation upright.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// putObject uploads data to the attachment bucket.
+func putObject(ctx context.Context, key, contentType string, data []byte) error {
+	w := api.AttachmentBucket.Upload(ctx, key, objects.WithUploadAttrs(objects.UploadAttrs{
+		ContentType: contentType,
+	}))
+	if _, err := w.Write(data); err != nil {
+		w.Abort(err)
+		return err
+	}
+	return w.Close()
+}
+
+// removeAttachmentObjects deletes the stored files of an attachment after
+// a failed upload. Objects that are already gone are not an error.
+func removeAttachmentObjects(ctx context.Context, id uuid.UUID, hasThumbnail bool) error {
+	keys := []string{id.String()}
+	if hasThumbnail {
+		keys = append(keys, thumbnailKey(id))
+	}
+	for _, key := range keys {
+		if err := api.AttachmentBucket.Remove(ctx, key); err != nil && !errors.Is(err, objects.ErrObjectNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachmentTransformer points attachment:ID links and images at the
+// stored file. Rendering does not look the attachment up.
+type attachmentTransformer struct{}
+
+func (attachmentTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	resolve := func(dest []byte) []byte {
+		ref, ok := bytes.CutPrefix(dest, []byte("attachment:"))
+		if !ok {
+			return dest
+		}
+		id, err := uuid.FromString(string(ref))
+		if err != nil {
+			return dest
+		}
+		return []byte(api.AttachmentBucket.PublicURL(id.String()).String())
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Image:
+			n.Destination = resolve(n.Destination)
+		case *ast.Link:
+			n.Destination = resolve(n.Destination)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// attachmentExtension adds attachment links to a goldmark renderer.
+type attachmentExtension struct{}
+
+func (attachmentExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(attachmentTransformer{}, 999),
+	))
+}
+
+//encore:api auth raw path=/app/attachments/upload
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	// Leave room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, `{"error":"File is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, `{"error":"A file is required"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		http.Error(w, `{"error":"Failed to read file"}`, http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxAttachmentSize {
+		http.Error(w, `{"error":"File is too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, `{"error":"File is empty"}`, http.StatusBadRequest)
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !attachmentTypes[contentType] {
+		http.Error(w, `{"error":"File type is not allowed"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	params := db.CreateAttachmentParams{
+		ID:          id,
+		UserID:      &current.UserID,
+		Filename:    cleanFilename(header.Filename),
+		ContentType: contentType,
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		img, err := processImage(data, contentType)
+		if err != nil {
+			if errors.Is(err, errImageTooLarge) {
+				http.Error(w, `{"error":"Image dimensions are too large"}`, http.StatusBadRequest)
+				return
+			}
+			http.Error(w, `{"error":"Invalid image"}`, http.StatusBadRequest)
+			return
+		}
+		data = img.data
+		params.Width = int32(img.width)
+		params.Height = int32(img.height)
+
+		if img.thumbnail != nil {
+			if err := putObject(r.Context(), thumbnailKey(id), img.thumbnailType, img.thumbnail); err != nil {
+				println("Attachment upload error:", err.Error())
+				http.Error(w, `{"error":"Failed to store file"}`, http.StatusInternalServerError)
+				return
+			}
+			params.HasThumbnail = true
+		}
+	}
+	params.Size = int64(len(data))
+
+	if err := putObject(r.Context(), id.String(), contentType, data); err != nil {
+		println("Attachment upload error:", err.Error())
+		removeAttachmentObjects(r.Context(), id, params.HasThumbnail)
+		http.Error(w, `{"error":"Failed to store file"}`, http.StatusInternalServerError)
+		return
+	}
+
+	attachment, err := api.CreateAttachment(r.Context(), params)
+	if err != nil {
+		removeAttachmentObjects(r.Context(), id, params.HasThumbnail)
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// A snippet the editor can insert to embed the attachment.
+	label := strings.NewReplacer("[", "", "]", "").Replace(attachment.Filename)
+	markdown := "[" + label + "](attachment:" + id.String() + ")"
+	if params.Width > 0 {
+		markdown = "!" + markdown
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"attachment": newAttachmentInfo(attachment),
+		"markdown":   markdown,
+	})
+}
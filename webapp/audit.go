@@ -0,0 +1,258 @@
+package webapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Types of events written to the audit log.
+const (
+	auditRegister         = "register"
+	auditLogin            = "login"
+	auditLoginTwoFactor   = "login_2fa"
+	auditLogout           = "logout"
+	auditSession          = "session"
+	auditPasswordChange   = "password_change"
+	auditPasswordReset    = "password_reset"
+	auditUsernameChange   = "username_change"
+	auditTwoFactorEnable  = "2fa_enable"
+	auditTwoFactorDisable = "2fa_disable"
+	auditRoleGrant        = "role_grant"
+	auditRoleRevoke       = "role_revoke"
+	auditLockoutClear     = "lockout_clear"
+)
+
+// Outcomes of an audited event. An error is a failure on our side, as
+// opposed to the caller being turned away.
+const (
+	auditSuccess = "success"
+	auditFailure = "failure"
+	auditError   = "error"
+)
+
+// auditRetentionKey is the site setting the api service reads when pruning
+// old audit events.
+const auditRetentionKey = "audit_retention_days"
+
+// truncate shortens s to at most n characters, which is how Postgres
+// measures VARCHAR columns. Invalid UTF-8, which the database would
+// reject, is replaced first.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// audit records a security event for the request r. userID is nil when the
+// request could not be tied to an account, in which case username is what
+// the client claimed to be. A failure to record is logged but never fails
+// the request itself.
+func audit(r *http.Request, event string, userID *uuid.UUID, username, outcome, detail string) {
+	ip := truncate(clientIP(r), 45)
+	username = truncate(username, 50)
+	userAgent := truncate(r.UserAgent(), 512)
+
+	if err := api.CreateAuditEvent(r.Context(), db.CreateAuditEventParams{
+		EventType: event,
+		UserID:    userID,
+		Username:  username,
+		IpAddress: ip,
+		UserAgent: userAgent,
+		Outcome:   outcome,
+		Detail:    detail,
+	}); err != nil {
+		println("Audit event error:", event, err.Error())
+	}
+}
+
+func auditEventJSON(e db.AuditEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         e.ID,
+		"event_type": e.EventType,
+		"user_id":    e.UserID,
+		"username":   e.Username,
+		"ip_address": e.IpAddress,
+		"user_agent": e.UserAgent,
+		"outcome":    e.Outcome,
+		"detail":     e.Detail,
+		"created_at": e.CreatedAt,
+	}
+}
+
+//encore:api auth raw path=/app/auth/security-activity
+func SecurityActivity(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.GetRecentAuditEventsForUser(r.Context(), api.GetRecentAuditEventsForUserParams{
+		UserID: current.UserID,
+		Limit:  50,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]map[string]interface{}, 0, len(res.Events))
+	for _, e := range res.Events {
+		events = append(events, auditEventJSON(e))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+	})
+}
+
+//encore:api auth raw path=/app/admin/audit
+func AuditEvents(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	var req struct {
+		EventType string    `json:"event_type"`
+		Username  string    `json:"username"`
+		IpAddress string    `json:"ip_address"`
+		Outcome   string    `json:"outcome"`
+		Since     time.Time `json:"since"`
+		Until     time.Time `json:"until"`
+		Limit     int32     `json:"limit"`
+		Offset    int32     `json:"offset"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Until.IsZero() {
+		req.Until = time.Now().Add(time.Minute)
+	}
+	if req.Limit <= 0 || req.Limit > 200 {
+		req.Limit = 50
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	res, err := api.GetAuditEvents(r.Context(), db.GetAuditEventsParams{
+		EventType: req.EventType,
+		Username:  req.Username,
+		IpAddress: req.IpAddress,
+		Outcome:   req.Outcome,
+		Since:     req.Since,
+		Until:     req.Until,
+		RowLimit:  req.Limit,
+		RowOffset: req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]map[string]interface{}, 0, len(res.Events))
+	for _, e := range res.Events {
+		events = append(events, auditEventJSON(e))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+	})
+}
+
+//encore:api auth raw path=/app/admin/audit/retention
+func SetAuditRetention(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Days int `json:"days"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if req.Days < 1 || req.Days > 3650 {
+		http.Error(w, `{"error":"Retention must be between 1 and 3650 days"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+		Key:   auditRetentionKey,
+		Value: strconv.Itoa(req.Days),
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"days":    req.Days,
+	})
+}
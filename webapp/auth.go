@@ -0,0 +1,160 @@
+package webapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+	"github.com/gorilla/securecookie"
+
+	"encore.app/api"
+)
+
+// AuthParams are the credentials a request can carry: a personal access
+// token in the Authorization header, or the markblog session cookie.
+type AuthParams struct {
+	Authorization string `header:"Authorization"`
+	Session       string `cookie:"markblog"`
+}
+
+// AuthData describes the authenticated caller of an endpoint. Exactly one
+// of SessionID and TokenID is set, depending on how the caller signed in.
+type AuthData struct {
+	UserID    uuid.UUID
+	Username  string
+	Roles     []string
+	SessionID *uuid.UUID
+	TokenID   *uuid.UUID
+	// Scopes limits what a token may do. Sessions are not limited.
+	Scopes []string
+}
+
+// HasScope reports whether the caller may act within scope.
+func (d *AuthData) HasScope(scope string) bool {
+	if d.TokenID == nil {
+		return true
+	}
+	for _, s := range d.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authData returns the caller resolved by AuthHandler, or nil for an
+// anonymous request to a public endpoint.
+func authData() *AuthData {
+	data, _ := auth.Data().(*AuthData)
+	return data
+}
+
+//encore:authhandler
+func AuthHandler(ctx context.Context, p *AuthParams) (auth.UID, *AuthData, error) {
+	var data *AuthData
+	var err error
+	if token, ok := parseBearer(p.Authorization); ok {
+		data, err = authenticateToken(ctx, token)
+	} else if p.Session != "" {
+		data, err = authenticateSession(ctx, p.Session)
+	} else {
+		err = errNoSession
+	}
+
+	if err != nil {
+		if errors.Is(err, errNoSession) {
+			return "", nil, &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
+		}
+		return "", nil, err
+	}
+
+	user, err := api.GetUserByID(ctx, data.UserID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil, &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
+		}
+		return "", nil, err
+	}
+
+	roles, err := api.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data.Username = user.Username
+	data.Roles = append([]string{roleUser}, roles.Roles...)
+
+	return auth.UID(user.ID.String()), data, nil
+}
+
+// sessionIDFromCookie decodes the markblog cookie value and returns the
+// server-side session it points to.
+func sessionIDFromCookie(value string) (uuid.UUID, bool) {
+	values := make(map[interface{}]interface{})
+	if err := securecookie.DecodeMulti("markblog", value, &values, store.Codecs...); err != nil {
+		return uuid.UUID{}, false
+	}
+
+	rawID, ok := values["session_id"].(string)
+	if !ok {
+		return uuid.UUID{}, false
+	}
+	id, err := uuid.FromString(rawID)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// authenticateSession resolves a session cookie to its user. Sessions still
+// awaiting their second factor do not count as authenticated.
+func authenticateSession(ctx context.Context, cookie string) (*AuthData, error) {
+	id, ok := sessionIDFromCookie(cookie)
+	if !ok {
+		return nil, errNoSession
+	}
+
+	s, err := api.GetActiveSessionByID(ctx, id)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errNoSession
+		}
+		return nil, err
+	}
+	if s.TwoFactorPending {
+		return nil, errNoSession
+	}
+
+	// Avoid a write on every request; minute precision is plenty for a
+	// device list.
+	if time.Since(s.LastSeenAt) > time.Minute {
+		if err := api.TouchSession(ctx, s.ID); err != nil {
+			println("Session touch error:", err.Error())
+		}
+	}
+
+	return &AuthData{UserID: s.UserID, SessionID: &s.ID}, nil
+}
+
+// authenticateToken resolves a personal access token to its user.
+func authenticateToken(ctx context.Context, token string) (*AuthData, error) {
+	t, err := api.GetAccessTokenByHash(ctx, hashAccessToken(token))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errNoSession
+		}
+		return nil, err
+	}
+
+	if time.Since(t.LastUsedAt) > time.Minute {
+		if err := api.TouchAccessToken(ctx, t.ID); err != nil {
+			println("Access token touch error:", err.Error())
+		}
+	}
+
+	return &AuthData{UserID: t.UserID, TokenID: &t.ID, Scopes: strings.Fields(t.Scopes)}, nil
+}
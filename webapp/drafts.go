@@ -0,0 +1,236 @@
+package webapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Post statuses. Drafts and scheduled posts are only visible to their
+// author until they are published.
+const (
+	postDraft     = "draft"
+	postScheduled = "scheduled"
+	postPublished = "published"
+)
+
+// maxScheduleAhead is how far in the future a post can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// viewerID returns the caller's user ID, or uuid.Nil for anonymous readers.
+func viewerID() uuid.UUID {
+	if current := authData(); current != nil {
+		return current.UserID
+	}
+	return uuid.Nil
+}
+
+// isVisible reports whether the caller may read a post.
+func isVisible(post *db.Post) bool {
+	return post.Status == postPublished || post.UserID == viewerID()
+}
+
+// publication checks the status and publish time asked for a post and
+// returns the publish time to store. Posts that are not scheduled are
+// stamped with the current time. On rejection it writes the error and
+// returns false.
+func publication(w http.ResponseWriter, status string, publishAt time.Time) (time.Time, bool) {
+	switch status {
+	case postDraft, postPublished:
+		return time.Now(), true
+	case postScheduled:
+		if !publishAt.After(time.Now()) {
+			http.Error(w, `{"error":"Publish time must be in the future"}`, http.StatusBadRequest)
+			return time.Time{}, false
+		}
+		if publishAt.After(time.Now().Add(maxScheduleAhead)) {
+			http.Error(w, `{"error":"Publish time is too far ahead"}`, http.StatusBadRequest)
+			return time.Time{}, false
+		}
+		return publishAt, true
+	default:
+		http.Error(w, `{"error":"Invalid status"}`, http.StatusBadRequest)
+		return time.Time{}, false
+	}
+}
+
+//encore:api auth raw path=/app/drafts
+func Drafts(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if !current.HasScope(scopeRead) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.GetUnpublishedPostsByUser(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	drafts := make([]map[string]interface{}, 0, len(res.Posts))
+	for _, p := range res.Posts {
+		drafts = append(drafts, map[string]interface{}{
+			"id":         p.ID,
+			"title":      p.Title,
+			"slug":       p.Slug,
+			"content":    p.Content,
+			"html":       renderMarkdown(p.Content),
+			"status":     p.Status,
+			"publish_at": p.PublishAt,
+			"updated_at": p.UpdatedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"drafts": drafts,
+	})
+}
+
+//encore:api auth raw path=/app/drafts/update
+func UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID    string    `json:"post_id"`
+		Content   string    `json:"content"`
+		Title     string    `json:"title"`
+		Status    string    `json:"status"`
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		http.Error(w, `{"error":"Title is too long"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Status == "" {
+		req.Status = postDraft
+	}
+	publishAt, ok := publication(w, req.Status, req.PublishAt)
+	if !ok {
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	// The limit depends on the kind of post, which cannot change.
+	existing, err := api.GetPostByID(r.Context(), postID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !checkContent(w, existing.Kind, req.Content) {
+		return
+	}
+	if existing.Kind == postArticle && title == "" {
+		http.Error(w, `{"error":"Articles need a title"}`, http.StatusBadRequest)
+		return
+	}
+
+	attachments, ok := checkAttachments(w, r.Context(), current.UserID, req.Content)
+	if !ok {
+		return
+	}
+
+	excerpt, words := summarize(req.Content)
+
+	post, err := api.UpdateDraft(r.Context(), api.UpdateDraftParams{
+		ID:        postID,
+		UserID:    current.UserID,
+		Content:   req.Content,
+		Excerpt:   excerpt,
+		WordCount: words,
+		Title:     title,
+		Status:    req.Status,
+		PublishAt: publishAt,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		if errs.Code(err) == errs.PermissionDenied {
+			http.Error(w, `{"error":"Only the author can update a draft"}`, http.StatusForbidden)
+			return
+		}
+		if errs.Code(err) == errs.FailedPrecondition {
+			http.Error(w, `{"error":"Post is already published"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tagPost(r.Context(), post.ID, post.Content)
+	attachPost(r.Context(), post.ID, attachments)
+	recordMentions(r.Context(), current.UserID, post.ID, nil, post.Content)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         post.ID,
+		"status":     post.Status,
+		"publish_at": post.PublishAt,
+		"permalink":  permalink(current.Username, post.Slug),
+	})
+}
@@ -0,0 +1,469 @@
+package webapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Purposes of the single-use tokens sent by mail, and how long each stays
+// valid.
+const (
+	emailTokenVerify = "verify_email"
+	emailTokenReset  = "reset_password"
+
+	emailVerifyTokenTTL   = 24 * time.Hour
+	passwordResetTokenTTL = time.Hour
+)
+
+func newEmailToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashEmailToken(token), nil
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validEmail reports whether email is a bare address such as
+// "user@example.com", without a display name or anything else around it.
+func validEmail(email string) bool {
+	if len(email) > 254 {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// publicLink builds an absolute link into the app for use in mail.
+func publicLink(path string, query url.Values) string {
+	base := strings.TrimSuffix(secrets.PublicURL, "/")
+	if base == "" {
+		base = "http://localhost:4000"
+	}
+	return base + path + "?" + query.Encode()
+}
+
+// sendEmailToken issues a fresh token for purpose, replacing any earlier
+// one, and mails it to email as a link to path.
+func sendEmailToken(ctx context.Context, user *db.User, email, purpose, path string, ttl time.Duration, subject, intro string) error {
+	token, hash, err := newEmailToken()
+	if err != nil {
+		return err
+	}
+
+	if err := api.DeleteEmailTokensForUser(ctx, db.DeleteEmailTokensForUserParams{
+		UserID:  user.ID,
+		Purpose: purpose,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := api.CreateEmailToken(ctx, db.CreateEmailTokenParams{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	expiry := strconv.Itoa(int(ttl.Hours())) + " hours"
+	if ttl == time.Hour {
+		expiry = "1 hour"
+	}
+
+	return mailer.Send(ctx, Message{
+		To:      email,
+		Subject: subject,
+		Body: "Hi " + user.Username + ",\n\n" +
+			intro + "\n\n" +
+			publicLink(path, url.Values{"token": {token}}) + "\n\n" +
+			"The link expires in " + expiry + ". If this wasn't you, you can ignore this message.\n",
+	})
+}
+
+//encore:api auth raw path=/app/auth/email
+func SetEmail(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email != "" && !validEmail(email) {
+		http.Error(w, `{"error":"Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The address decides where password resets go, so changing it takes
+	// the password just like changing the password itself.
+	if ok, _ := verifyPassword(r.Context(), user.PasswordHash, req.Password); !ok {
+		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if err := api.SetUserEmail(r.Context(), db.SetUserEmailParams{
+		ID:    user.ID,
+		Email: email,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Reset links already sent to the old address must stop working.
+	if err := api.DeleteEmailTokensForUser(r.Context(), db.DeleteEmailTokensForUserParams{
+		UserID:  user.ID,
+		Purpose: emailTokenReset,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if email == "" {
+		if err := api.DeleteEmailTokensForUser(r.Context(), db.DeleteEmailTokensForUserParams{
+			UserID:  user.ID,
+			Purpose: emailTokenVerify,
+		}); err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+		})
+		return
+	}
+
+	if err := sendEmailToken(r.Context(), user, email, emailTokenVerify, "/verify-email", emailVerifyTokenTTL,
+		"Confirm your email address",
+		"Open this link to confirm that this address belongs to your markblog account:",
+	); err != nil {
+		println("Verification mail error:", err.Error())
+		http.Error(w, `{"error":"Failed to send verification email"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":           true,
+		"verification_sent": true,
+	})
+}
+
+//encore:api auth raw path=/app/auth/email/status
+func EmailStatus(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"email":    user.Email,
+		"verified": user.EmailVerified,
+	})
+}
+
+//encore:api public raw path=/app/auth/email/verify
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	t, err := api.ConsumeEmailToken(r.Context(), db.ConsumeEmailTokenParams{
+		TokenHash: hashEmailToken(req.Token),
+		Purpose:   emailTokenVerify,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	owner, err := api.GetUserByVerifiedEmail(r.Context(), t.Email)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if err == nil && owner.ID != t.UserID {
+		http.Error(w, `{"error":"Email is already in use"}`, http.StatusConflict)
+		return
+	}
+
+	res, err := api.MarkUserEmailVerified(r.Context(), db.MarkUserEmailVerifiedParams{
+		ID:    t.UserID,
+		Email: t.Email,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The address was changed again after this token was sent.
+	if !res.Verified {
+		http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+//encore:api public raw path=/app/auth/password/forgot
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if !validEmail(email) {
+		http.Error(w, `{"error":"Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+
+	lockedUntil, err := throttlePasswordReset(r.Context(), email, clientIP(r))
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+		http.Error(w, `{"error":"Too many requests, try again later"}`, http.StatusTooManyRequests)
+		return
+	}
+
+	// The answer is the same whether or not the address belongs to anyone,
+	// so this endpoint cannot be used to find out who is registered.
+	user, err := api.GetUserByVerifiedEmail(r.Context(), email)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil {
+		if err := sendEmailToken(r.Context(), user, user.Email, emailTokenReset, "/reset-password", passwordResetTokenTTL,
+			"Reset your password",
+			"Someone asked to reset the password of your markblog account. Open this link to choose a new one:",
+		); err != nil {
+			println("Password reset mail error:", err.Error())
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+//encore:api public raw path=/app/auth/password/reset
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.NewPassword) < 8 {
+		http.Error(w, `{"error":"Password must be at least 8 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	t, err := api.ConsumeEmailToken(r.Context(), db.ConsumeEmailTokenParams{
+		TokenHash: hashEmailToken(req.Token),
+		Purpose:   emailTokenReset,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), t.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	hashedPassword, err := hashPassword(r.Context(), req.NewPassword)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to secure password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := api.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hashedPassword,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever got hold of the account may still be signed in.
+	if _, err := api.DeleteSessionsForUser(r.Context(), user.ID); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := clearLoginFailures(r.Context(), user.Username); err != nil {
+		println("Login failure clear error:", err.Error())
+	}
+
+	audit(r, auditPasswordReset, &user.ID, user.Username, auditSuccess, "")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,251 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"encore.dev"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// requireDatabase skips tests that need the database, which only exists
+// when they are run through `encore test`.
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if m := encore.Meta(); m == nil || m.Environment.Type != encore.EnvTest {
+		t.Skip("needs the Encore test runtime")
+	}
+}
+
+// testMailer returns the mailer the app sends through, which is a
+// memoryMailer since tests run without an SMTP server.
+func testMailer(t *testing.T) *memoryMailer {
+	t.Helper()
+	m, ok := mailer.(*memoryMailer)
+	if !ok {
+		t.Skip("an SMTP server is configured")
+	}
+	return m
+}
+
+// createTestUser creates an account with a unique name and the password
+// "password1".
+func createTestUser(t *testing.T, ctx context.Context) *db.User {
+	t.Helper()
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashPassword(ctx, "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := api.CreateUser(ctx, db.CreateUserParams{
+		Username:     "t" + strings.ReplaceAll(id.String(), "-", "")[:12],
+		PasswordHash: hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// testIP returns the n-th client address for a test identified by id, so
+// that throttling left over from earlier runs does not get in the way.
+func testIP(id uuid.UUID, n int) string {
+	return fmt.Sprintf("10.%d.%d.%d", id[0], id[1], n)
+}
+
+// postJSON calls handler the way the frontend would, from an allowed origin
+// and the client address ip.
+func postJSON(handler http.HandlerFunc, ip, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Origin", "http://localhost:4000")
+	r.RemoteAddr = ip + ":4321"
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// lastToken returns the token linked to from the last mail sent to email,
+// and checks that the link points at path.
+func lastToken(t *testing.T, m *memoryMailer, email, path string) string {
+	t.Helper()
+	sent := m.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].To != email {
+			continue
+		}
+		for _, field := range strings.Fields(sent[i].Body) {
+			u, err := url.Parse(field)
+			if err != nil || u.Scheme == "" {
+				continue
+			}
+			if u.Path != path {
+				t.Fatalf("mail links to %s, want %s", u.Path, path)
+			}
+			return u.Query().Get("token")
+		}
+		t.Fatalf("mail to %s has no link", email)
+	}
+	t.Fatalf("no mail sent to %s", email)
+	return ""
+}
+
+func TestMemoryMailerKeepsMessages(t *testing.T) {
+	m := &memoryMailer{}
+	msgs := []Message{
+		{To: "a@example.com", Subject: "First", Body: "one"},
+		{To: "b@example.com", Subject: "Second", Body: "two"},
+	}
+	for _, msg := range msgs {
+		if err := m.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent := m.Sent()
+	if len(sent) != len(msgs) {
+		t.Fatalf("got %d messages, want %d", len(sent), len(msgs))
+	}
+	for i := range msgs {
+		if sent[i] != msgs[i] {
+			t.Errorf("message %d is %+v, want %+v", i, sent[i], msgs[i])
+		}
+	}
+
+	// Sent hands out a copy.
+	sent[0].To = "changed@example.com"
+	if m.Sent()[0].To != "a@example.com" {
+		t.Error("Sent exposes the mailer's own slice")
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	requireDatabase(t)
+	m := testMailer(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, ctx)
+	ip := testIP(user.ID, 1)
+	email := user.Username + "@example.com"
+	if err := api.SetUserEmail(ctx, db.SetUserEmailParams{ID: user.ID, Email: email}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendEmailToken(ctx, user, email, emailTokenVerify, "/verify-email", emailVerifyTokenTTL, "Subject", "Intro"); err != nil {
+		t.Fatal(err)
+	}
+	token := lastToken(t, m, email, "/verify-email")
+
+	if w := postJSON(VerifyEmail, ip, `{"token":"wrong"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("wrong token: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	if w := postJSON(VerifyEmail, ip, `{"token":"`+token+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	got, err := api.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.EmailVerified || got.Email != email {
+		t.Fatalf("email is %q, verified %v; want %q verified", got.Email, got.EmailVerified, email)
+	}
+
+	if w := postJSON(VerifyEmail, ip, `{"token":"`+token+`"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("reused token: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	requireDatabase(t)
+	m := testMailer(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, ctx)
+	ip := testIP(user.ID, 1)
+	email := user.Username + "@example.com"
+	if err := api.SetUserEmail(ctx, db.SetUserEmailParams{ID: user.ID, Email: email}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.MarkUserEmailVerified(ctx, db.MarkUserEmailVerifiedParams{ID: user.ID, Email: email}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown addresses get the same answer, but no mail.
+	before := len(m.Sent())
+	if w := postJSON(ForgotPassword, ip, `{"email":"nobody-`+email+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("unknown address: got status %d: %s", w.Code, w.Body)
+	}
+	if len(m.Sent()) != before {
+		t.Fatal("mail sent to an unknown address")
+	}
+
+	if w := postJSON(ForgotPassword, ip, `{"email":"`+email+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	token := lastToken(t, m, email, "/reset-password")
+
+	body := `{"token":"` + token + `","new_password":"password2"}`
+	if w := postJSON(ResetPassword, ip, body); w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	got, err := api.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := verifyPassword(ctx, got.PasswordHash, "password2"); !ok {
+		t.Fatal("new password does not verify")
+	}
+	if ok, _ := verifyPassword(ctx, got.PasswordHash, "password1"); ok {
+		t.Fatal("old password still verifies")
+	}
+
+	if w := postJSON(ResetPassword, ip, body); w.Code != http.StatusBadRequest {
+		t.Fatalf("reused token: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestForgotPasswordThrottle(t *testing.T) {
+	requireDatabase(t)
+	testMailer(t)
+
+	// Addresses are made unique so that earlier runs do not count.
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := strings.ReplaceAll(id.String(), "-", "")[:12]
+
+	// Per address, from different IPs.
+	for i := 0; i < resetEmailFreeAttempts; i++ {
+		if w := postJSON(ForgotPassword, testIP(id, i), `{"email":"`+prefix+`@example.com"}`); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d: %s", i+1, w.Code, w.Body)
+		}
+	}
+	w := postJSON(ForgotPassword, testIP(id, 100), `{"email":"`+strings.ToUpper(prefix)+`@example.com"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("address over the limit: got status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	// Per IP, for different addresses.
+	for i := 0; i < resetIPFreeAttempts; i++ {
+		email := prefix + "-" + strconv.Itoa(i) + "@example.com"
+		if w := postJSON(ForgotPassword, testIP(id, 200), `{"email":"`+email+`"}`); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d: %s", i+1, w.Code, w.Body)
+		}
+	}
+	if w := postJSON(ForgotPassword, testIP(id, 200), `{"email":"`+prefix+`-last@example.com"}`); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("IP over the limit: got status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
@@ -82,14 +82,25 @@ export namespace webapp {
     constructor(baseClient: BaseClient) {
       this.baseClient = baseClient
       this.Activity = this.Activity.bind(this)
+      this.Challenge = this.Challenge.bind(this)
       this.CheckAuth = this.CheckAuth.bind(this)
       this.Comment = this.Comment.bind(this)
       this.Discussion = this.Discussion.bind(this)
       this.Feed = this.Feed.bind(this)
       this.Login = this.Login.bind(this)
       this.Logout = this.Logout.bind(this)
+      this.Mentions = this.Mentions.bind(this)
+      this.Permalink = this.Permalink.bind(this)
       this.Post = this.Post.bind(this)
+      this.PostBody = this.PostBody.bind(this)
+      this.ReactionEmoji = this.ReactionEmoji.bind(this)
+      this.Reactions = this.Reactions.bind(this)
       this.Register = this.Register.bind(this)
+      this.TagAutocomplete = this.TagAutocomplete.bind(this)
+      this.TagFeed = this.TagFeed.bind(this)
+      this.ToggleReaction = this.ToggleReaction.bind(this)
+      this.TrendingTags = this.TrendingTags.bind(this)
+      this.UploadAttachment = this.UploadAttachment.bind(this)
     }
 
     public async Activity(
@@ -100,6 +111,14 @@ export namespace webapp {
       return this.baseClient.callAPI(method, `/app/activity`, body, options)
     }
 
+    public async Challenge(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/auth/challenge`, body, options)
+    }
+
     public async CheckAuth(
       method: string,
       body?: BodyInit,
@@ -148,6 +167,22 @@ export namespace webapp {
       return this.baseClient.callAPI(method, `/app/auth/logout`, body, options)
     }
 
+    public async Mentions(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/mentions`, body, options)
+    }
+
+    public async Permalink(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/permalink`, body, options)
+    }
+
     public async Post(
       method: string,
       body?: BodyInit,
@@ -156,6 +191,30 @@ export namespace webapp {
       return this.baseClient.callAPI(method, `/app/post`, body, options)
     }
 
+    public async PostBody(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/post/body`, body, options)
+    }
+
+    public async ReactionEmoji(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/reactions/emoji`, body, options)
+    }
+
+    public async Reactions(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/reactions`, body, options)
+    }
+
     public async Register(
       method: string,
       body?: BodyInit,
@@ -163,6 +222,68 @@ export namespace webapp {
     ): Promise<globalThis.Response> {
       return this.baseClient.callAPI(method, `/app/auth/register`, body, options)
     }
+
+    public async ResetPassword(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/auth/password/reset`, body, options)
+    }
+
+    public async TagAutocomplete(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/autocomplete/tags`, body, options)
+    }
+
+    public async TagFeed(
+      method: string,
+      tag: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(
+        method,
+        `/app/tags/${encodeURIComponent(tag)}`,
+        body,
+        options,
+      )
+    }
+
+    public async ToggleReaction(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/reactions/toggle`, body, options)
+    }
+
+    public async TrendingTags(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/trending/tags`, body, options)
+    }
+
+    public async UploadAttachment(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/attachments/upload`, body, options)
+    }
+
+    public async VerifyEmail(
+      method: string,
+      body?: BodyInit,
+      options?: CallParameters,
+    ): Promise<globalThis.Response> {
+      return this.baseClient.callAPI(method, `/app/auth/email/verify`, body, options)
+    }
   }
 }
 
@@ -9,6 +9,28 @@ const router = createRouter({
       name: 'home',
       component: HomeView,
     },
+    {
+      // Mention links point at profiles, shown as the user's activity.
+      path: '/@:username',
+      name: 'profile',
+      component: HomeView,
+    },
+    {
+      path: '/@:username/:slug',
+      name: 'post',
+      component: () => import('../views/PostView.vue'),
+    },
+    {
+      // Linked from the verification and password reset mails.
+      path: '/verify-email',
+      name: 'verify-email',
+      component: () => import('../views/VerifyEmailView.vue'),
+    },
+    {
+      path: '/reset-password',
+      name: 'reset-password',
+      component: () => import('../views/ResetPasswordView.vue'),
+    },
     /*
     {
       path: '/about',
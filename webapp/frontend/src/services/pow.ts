@@ -0,0 +1,50 @@
+import type Client from '../client'
+
+export type PowPurpose = 'register' | 'post'
+
+export interface PowFields {
+  pow_challenge?: string
+  pow_solution?: string
+}
+
+function leadingZeroBits(bytes: Uint8Array): number {
+  let zeros = 0
+  for (const b of bytes) {
+    if (b === 0) {
+      zeros += 8
+      continue
+    }
+    zeros += Math.clz32(b) - 24
+    break
+  }
+  return zeros
+}
+
+// Fetches a proof-of-work challenge for the given purpose and searches for
+// a counter whose SHA-256 together with the challenge starts with enough
+// zero bits. Resolves to the fields to merge into the request body, which
+// are empty when the server does not ask for proof of work.
+export async function proofOfWork(client: Client, purpose: PowPurpose): Promise<PowFields> {
+  const response = await client.webapp.Challenge('GET', undefined, {
+    query: { purpose },
+  })
+  if (!response.ok) {
+    throw new Error(`HTTP error! status: ${response.status}`)
+  }
+
+  const data = await response.json()
+  if (!data['required']) {
+    return {}
+  }
+
+  const challenge: string = data['challenge']
+  const difficulty: number = data['difficulty']
+  const encoder = new TextEncoder()
+  for (let counter = 0; ; counter++) {
+    const solution = counter.toString(36)
+    const digest = await crypto.subtle.digest('SHA-256', encoder.encode(challenge + ':' + solution))
+    if (leadingZeroBits(new Uint8Array(digest)) >= difficulty) {
+      return { pow_challenge: challenge, pow_solution: solution }
+    }
+  }
+}
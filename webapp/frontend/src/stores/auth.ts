@@ -2,6 +2,7 @@ import { defineStore } from 'pinia'
 import { ref } from 'vue'
 import Client, { Local } from '../client'
 import { useAlert } from '@/services/alert'
+import { proofOfWork } from '@/services/pow'
 
 const alert = useAlert()
 
@@ -9,6 +10,8 @@ export const useAuthStore = defineStore('auth', () => {
   const isAuthenticated = ref(false)
   const isLoading = ref(false)
   const username = ref('')
+  // Sent back on every state-changing request; see csrfHeaders.
+  const csrfToken = ref('')
   const client = new Client(Local, {
     requestInit: {
       credentials: 'include',
@@ -21,6 +24,7 @@ export const useAuthStore = defineStore('auth', () => {
       const response = await client.webapp.CheckAuth('GET')
       const data = await response.json()
       isAuthenticated.value = data['authenticated']
+      csrfToken.value = data['csrf_token'] ?? ''
       username.value = data['user']['username']
     } catch (error) {
       console.error('Auth check failed:', error)
@@ -31,6 +35,10 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
+  function csrfHeaders(): Record<string, string> {
+    return csrfToken.value ? { 'X-CSRF-Token': csrfToken.value } : {}
+  }
+
   async function login(credentials: { username: string; password: string }) {
     isLoading.value = true
     try {
@@ -43,6 +51,7 @@ export const useAuthStore = defineStore('auth', () => {
         {
           headers: {
             'Content-Type': 'application/json',
+            ...csrfHeaders(),
           },
         },
       )
@@ -64,15 +73,18 @@ export const useAuthStore = defineStore('auth', () => {
   async function register(credentials: { username: string; password: string }) {
     isLoading.value = true
     try {
+      const pow = await proofOfWork(client, 'register')
       const response = await client.webapp.Register(
         'POST',
         JSON.stringify({
           username: credentials.username,
           password: credentials.password,
+          ...pow,
         }),
         {
           headers: {
             'Content-Type': 'application/json',
+            ...csrfHeaders(),
           },
         },
       )
@@ -95,7 +107,10 @@ export const useAuthStore = defineStore('auth', () => {
     isLoading.value = true
     try {
       isAuthenticated.value = false
-      const response = await client.webapp.Logout('GET')
+      const response = await client.webapp.Logout('POST', undefined, {
+        headers: csrfHeaders(),
+      })
+      csrfToken.value = ''
       const data = await response.json()
       console.log('Logout successful:', data)
     } catch (error) {
@@ -113,6 +128,7 @@ export const useAuthStore = defineStore('auth', () => {
     isAuthenticated,
     username,
     isLoading,
+    csrfHeaders,
     checkAuth,
     login,
     register,
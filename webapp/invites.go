@@ -0,0 +1,487 @@
+package webapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Registration policies an admin can choose between. Without a stored
+// setting, registration is open.
+const (
+	registrationOpen       = "open"
+	registrationInviteOnly = "invite_only"
+	registrationClosed     = "closed"
+)
+
+const registrationPolicyKey = "registration_policy"
+
+// Bounds on what an invite may be created with.
+const (
+	inviteMaxUsesLimit = 100
+	inviteMaxTTL       = 30 * 24 * time.Hour
+	inviteDefaultTTL   = 7 * 24 * time.Hour
+)
+
+// inviteAlphabet leaves out characters that are easily confused when an
+// invite code is read aloud or typed from paper.
+const inviteAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func registrationPolicy(ctx context.Context) (string, error) {
+	res, err := api.GetSiteSetting(ctx, registrationPolicyKey)
+	if err != nil {
+		if isNotFound(err) {
+			return registrationOpen, nil
+		}
+		return "", err
+	}
+	return res.Value, nil
+}
+
+func newInviteCode() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, len(b))
+	for i, c := range b {
+		code[i] = inviteAlphabet[int(c)%len(inviteAlphabet)]
+	}
+	return string(code[:4]) + "-" + string(code[4:8]) + "-" + string(code[8:]), nil
+}
+
+//encore:api public raw path=/app/auth/registration
+func RegistrationPolicy(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	policy, err := registrationPolicy(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy": policy,
+	})
+}
+
+//encore:api auth raw path=/app/admin/registration
+func SetRegistrationPolicy(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Policy string `json:"policy"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	switch req.Policy {
+	case registrationOpen, registrationInviteOnly, registrationClosed:
+	default:
+		http.Error(w, `{"error":"Unknown registration policy"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+		Key:   registrationPolicyKey,
+		Value: req.Policy,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"policy":  req.Policy,
+	})
+}
+
+//encore:api auth raw path=/app/invites
+func Invites(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.GetInvitesCreatedBy(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	invites := make([]map[string]interface{}, 0, len(res.Invites))
+	for _, i := range res.Invites {
+		invites = append(invites, map[string]interface{}{
+			"id":         i.ID,
+			"code":       i.Code,
+			"max_uses":   i.MaxUses,
+			"uses":       i.Uses,
+			"expires_at": i.ExpiresAt,
+			"created_at": i.CreatedAt,
+			"active":     i.Uses < i.MaxUses && i.ExpiresAt.After(time.Now()),
+		})
+	}
+
+	out := map[string]interface{}{
+		"invites": invites,
+	}
+	// Admins are not limited, so they have no remaining count.
+	if !current.HasRole(roleAdmin) {
+		quota, err := api.GetInviteQuota(r.Context(), current.UserID)
+		if err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+		spent, err := api.CountInviteUsesCreatedBy(r.Context(), current.UserID)
+		if err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+		remaining := int64(quota.Quota) - spent.Uses
+		if remaining < 0 {
+			remaining = 0
+		}
+		out["remaining"] = remaining
+	}
+
+	json.NewEncoder(w).Encode(out)
+}
+
+//encore:api auth raw path=/app/invites/create
+func CreateInvite(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		MaxUses       int32 `json:"max_uses"`
+		ExpiresInDays int   `json:"expires_in_days"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 1 || req.MaxUses > inviteMaxUsesLimit {
+		http.Error(w, `{"error":"Invite uses must be between 1 and 100"}`, http.StatusBadRequest)
+		return
+	}
+
+	ttl := inviteDefaultTTL
+	if req.ExpiresInDays != 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > inviteMaxTTL {
+		http.Error(w, `{"error":"Invite expiry must be between 1 and 30 days"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate invite"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Every use an invite allows counts against the quota, so that one
+	// invite cannot let in more people than the quota does.
+	var invite *db.Invite
+	if current.HasRole(roleAdmin) {
+		invite, err = api.CreateInvite(r.Context(), db.CreateInviteParams{
+			Code:      code,
+			CreatedBy: &current.UserID,
+			MaxUses:   req.MaxUses,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	} else {
+		invite, err = api.CreateInviteWithinQuota(r.Context(), api.CreateInviteWithinQuotaParams{
+			Code:      code,
+			CreatedBy: current.UserID,
+			MaxUses:   req.MaxUses,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	}
+	if err != nil {
+		if errs.Code(err) == errs.ResourceExhausted {
+			http.Error(w, `{"error":"Invite quota exhausted"}`, http.StatusForbidden)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         invite.ID,
+		"code":       invite.Code,
+		"max_uses":   invite.MaxUses,
+		"expires_at": invite.ExpiresAt,
+	})
+}
+
+//encore:api auth raw path=/app/invites/revoke
+func RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.RevokeInvite(r.Context(), db.RevokeInviteParams{
+		ID:        req.ID,
+		CreatedBy: &current.UserID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !res.Revoked {
+		http.Error(w, `{"error":"Invite not found"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+//encore:api auth raw path=/app/admin/invites/redemptions
+func InviteRedemptions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !requireRole(w, authData(), roleModerator) {
+		return
+	}
+
+	var req struct {
+		Limit  int32 `json:"limit"`
+		Offset int32 `json:"offset"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 50
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	res, err := api.GetInviteRedemptions(r.Context(), db.GetInviteRedemptionsParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	redemptions := make([]map[string]interface{}, 0, len(res.Redemptions))
+	for _, rd := range res.Redemptions {
+		redemptions = append(redemptions, map[string]interface{}{
+			"user_id":             rd.UserID,
+			"username":            rd.Username,
+			"invited_by":          rd.InvitedBy,
+			"invited_by_username": rd.InvitedByUsername,
+			"code":                rd.Code,
+			"redeemed_at":         rd.RedeemedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"redemptions": redemptions,
+	})
+}
+
+//encore:api auth raw path=/app/admin/invites/quota
+func SetInviteQuota(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Quota    int32  `json:"quota"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if req.Quota < 0 {
+		http.Error(w, `{"error":"Quota cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := api.GetUserByUsername(r.Context(), strings.TrimSpace(req.Username))
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := api.SetInviteQuota(r.Context(), db.SetInviteQuotaParams{
+		UserID: user.ID,
+		Quota:  req.Quota,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,310 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Failed logins are tracked per username and per client IP. Once the free
+// attempts are used up, every further failure doubles the lockout, up to
+// lockoutMax. IPs get more slack since many users can share one.
+const (
+	usernameFreeAttempts = 5
+	ipFreeAttempts       = 20
+	lockoutBase          = 30 * time.Second
+	lockoutMax           = time.Hour
+)
+
+// Password reset requests are throttled the same way, per address and per
+// client IP, but with far fewer free requests since each one sends mail.
+const (
+	resetEmailFreeAttempts = 3
+	resetIPFreeAttempts    = 10
+)
+
+// dummyHashes caches a hash made with each set of argon2id parameters in
+// use, keyed by the hasher.
+var dummyHashes sync.Map
+
+// dummyPasswordHash returns a hash to check against when the username does
+// not exist, so that an unknown user takes as long to reject as a wrong
+// password. It is made with the current parameters, which real hashes are
+// upgraded to as their users log in.
+func dummyPasswordHash(ctx context.Context) string {
+	h := currentHasher(ctx)
+	if hash, ok := dummyHashes.Load(*h); ok {
+		return hash.(string)
+	}
+	hash, err := h.Hash("markblog")
+	if err != nil {
+		println("Dummy password hash error:", err.Error())
+		return ""
+	}
+	dummyHashes.Store(*h, hash)
+	return hash
+}
+
+// lockoutDuration returns how long to lock a key out after its failures-th
+// consecutive failure.
+func lockoutDuration(failures, free int32) time.Duration {
+	if failures < free {
+		return 0
+	}
+	d := lockoutBase
+	for i := free; i < failures && d < lockoutMax; i++ {
+		d *= 2
+	}
+	if d > lockoutMax {
+		d = lockoutMax
+	}
+	return d
+}
+
+// loginLockedUntil returns when the lockout on username or ip ends, or the
+// zero time if neither is locked out.
+func loginLockedUntil(ctx context.Context, username, ip string) (time.Time, error) {
+	res, err := api.GetActiveLoginLocks(ctx, db.GetActiveLoginLocksParams{
+		Username:  username,
+		IpAddress: ip,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var until time.Time
+	for _, a := range res.Attempts {
+		if a.LockedUntil.After(until) {
+			until = a.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// recordLoginFailure counts a failed login against both username and ip and
+// locks out whichever has run out of free attempts.
+func recordLoginFailure(ctx context.Context, username, ip string) error {
+	keys := []struct {
+		kind string
+		key  string
+		free int32
+	}{
+		{"username", username, usernameFreeAttempts},
+		{"ip", ip, ipFreeAttempts},
+	}
+
+	for _, k := range keys {
+		attempt, err := api.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Kind: k.kind,
+			Key:  k.key,
+		})
+		if err != nil {
+			return err
+		}
+
+		if d := lockoutDuration(attempt.Failures, k.free); d > 0 {
+			if err := api.SetLoginLockedUntil(ctx, db.SetLoginLockedUntilParams{
+				Kind:        k.kind,
+				Key:         k.key,
+				LockedUntil: time.Now().Add(d),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// throttlePasswordReset counts a password reset request against email and
+// ip and returns when the lockout on either ends, or the zero time if the
+// request may go ahead. Using up the last free request locks out the next
+// ones rather than this one.
+func throttlePasswordReset(ctx context.Context, email, ip string) (time.Time, error) {
+	keys := []struct {
+		kind string
+		key  string
+		free int32
+	}{
+		{"reset_email", strings.ToLower(email), resetEmailFreeAttempts},
+		{"reset_ip", ip, resetIPFreeAttempts},
+	}
+
+	var until time.Time
+	for _, k := range keys {
+		attempt, err := api.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Kind: k.kind,
+			Key:  k.key,
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if attempt.LockedUntil.After(time.Now()) {
+			if attempt.LockedUntil.After(until) {
+				until = attempt.LockedUntil
+			}
+			continue
+		}
+
+		if d := lockoutDuration(attempt.Failures, k.free); d > 0 {
+			if err := api.SetLoginLockedUntil(ctx, db.SetLoginLockedUntilParams{
+				Kind:        k.kind,
+				Key:         k.key,
+				LockedUntil: time.Now().Add(d),
+			}); err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+	return until, nil
+}
+
+// clearLoginFailures resets the failure count of username after a
+// successful login. The IP count is left alone, or a single valid account
+// could be used to keep resetting it.
+func clearLoginFailures(ctx context.Context, username string) error {
+	_, err := api.ClearLoginAttempts(ctx, db.ClearLoginAttemptsParams{
+		Kind: "username",
+		Key:  username,
+	})
+	return err
+}
+
+//encore:api auth raw path=/app/admin/lockouts
+func Lockouts(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	var req struct {
+		Limit  int32 `json:"limit"`
+		Offset int32 `json:"offset"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Limit <= 0 || req.Limit > 200 {
+		req.Limit = 50
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	res, err := api.GetLockedLoginAttempts(r.Context(), db.GetLockedLoginAttemptsParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	lockouts := make([]map[string]interface{}, 0, len(res.Attempts))
+	for _, a := range res.Attempts {
+		lockouts = append(lockouts, map[string]interface{}{
+			"kind":            a.Kind,
+			"key":             a.Key,
+			"failures":        a.Failures,
+			"last_failure_at": a.LastFailureAt,
+			"locked_until":    a.LockedUntil,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lockouts": lockouts,
+	})
+}
+
+//encore:api auth raw path=/app/admin/lockouts/clear
+func ClearLockout(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Kind string `json:"kind"`
+		Key  string `json:"key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !requireRole(w, current, roleAdmin) {
+		return
+	}
+
+	switch req.Kind {
+	case "username", "ip", "reset_email", "reset_ip":
+	default:
+		http.Error(w, `{"error":"Unknown lockout kind"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		http.Error(w, `{"error":"Key is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	res, err := api.ClearLoginAttempts(r.Context(), db.ClearLoginAttemptsParams{
+		Kind: req.Kind,
+		Key:  req.Key,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if res.Cleared {
+		username := ""
+		if req.Kind == "username" {
+			username = req.Key
+		}
+		audit(r, auditLockoutClear, nil, username, auditSuccess, req.Kind+" "+req.Key+" cleared by "+current.Username)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"cleared": res.Cleared,
+	})
+}
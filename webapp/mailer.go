@@ -0,0 +1,99 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email on behalf of the app.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// mailer is what the app sends mail through. Without an SMTP server
+// configured, mail stays in memory and is printed to the log instead.
+var mailer = newMailer()
+
+func newMailer() Mailer {
+	if secrets.SMTPServer == "" {
+		return &memoryMailer{}
+	}
+
+	var auth smtp.Auth
+	if secrets.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(secrets.SMTPServer)
+		auth = smtp.PlainAuth("", secrets.SMTPUsername, secrets.SMTPPassword, host)
+	}
+	return &smtpMailer{
+		addr: secrets.SMTPServer,
+		from: secrets.MailFrom,
+		auth: auth,
+	}
+}
+
+// smtpMailer sends mail through an SMTP relay.
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(m.addr, m.auth, from.Address, []string{to.Address}, []byte(b.String()))
+}
+
+// memoryMailer keeps every message instead of delivering it. It stands in
+// for a mail server during local development and in tests.
+type memoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *memoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	println("Mail to", msg.To+":", msg.Subject+"\n"+msg.Body)
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first.
+func (m *memoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
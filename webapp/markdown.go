@@ -0,0 +1,166 @@
+package webapp
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+
+	"encore.app/api/db"
+)
+
+// markdownVersion is part of every cache key. Bump it whenever the renderer
+// or the sanitizer policy changes, so that stale output is not served.
+const markdownVersion = "3"
+
+// markdownCacheBytes is how much rendered HTML is kept in memory. Articles
+// can render to hundreds of kilobytes, so the cache is bounded by size
+// rather than by the number of documents.
+const markdownCacheBytes = 32 << 20
+
+// markdown renders CommonMark with the GitHub extensions: tables,
+// strikethrough, autolinks and task lists, links @mentions to profiles and
+// points attachment: references at the uploaded files. Single newlines
+// become line breaks, as they do in the editor preview. Raw HTML in the
+// source is dropped by goldmark and anything else is left to the sanitizer.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, mentionExtension{}, attachmentExtension{}),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// markdownPolicy allows the HTML that Markdown can produce and nothing that
+// runs script. Images may still come from any http(s) URL, not just the
+// attachment bucket, so reading a post can reveal the reader's address to
+// whoever hosts them.
+var markdownPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowStyles("text-align").MatchingEnum("left", "right", "center").OnElements("th", "td")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+type markdownEntry struct {
+	key  string
+	html string
+}
+
+func (e *markdownEntry) size() int {
+	return len(e.key) + len(e.html)
+}
+
+// markdownLRU holds rendered documents keyed by a hash of their source,
+// evicting the least recently used once they take up more than maxBytes.
+// Documents bigger than a quarter of that are not kept at all, so that one
+// of them cannot flush the rest.
+type markdownLRU struct {
+	sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+func newMarkdownLRU(maxBytes int) *markdownLRU {
+	return &markdownLRU{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *markdownLRU) get(key string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*markdownEntry).html, true
+}
+
+func (c *markdownLRU) add(key, html string) {
+	entry := &markdownEntry{key: key, html: html}
+	if entry.size() > c.maxBytes/4 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size()
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*markdownEntry)
+		delete(c.entries, evicted.key)
+		c.size -= evicted.size()
+	}
+}
+
+var markdownCache = newMarkdownLRU(markdownCacheBytes)
+
+// renderMarkdown returns the sanitized HTML for a Markdown source. If the
+// source cannot be rendered, its text is returned escaped instead.
+func renderMarkdown(source string) string {
+	sum := sha256.Sum256([]byte(markdownVersion + "\x00" + source))
+	key := hex.EncodeToString(sum[:])
+
+	if out, ok := markdownCache.get(key); ok {
+		return out
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		println("Markdown render error:", err.Error())
+		return bluemonday.StrictPolicy().Sanitize(source)
+	}
+	out := markdownPolicy.Sanitize(buf.String())
+	markdownCache.add(key, out)
+	return out
+}
+
+// renderedPost and renderedComment are feed and discussion entries with
+// their content rendered to HTML alongside the Markdown source. Posts also
+// carry the path they are served at and how long they take to read, and
+// their attachments with download URLs in place of the stored metadata.
+type renderedPost struct {
+	db.GetLatestPostsRow
+	HTML        string `json:"html"`
+	Permalink   string `json:"permalink"`
+	ReadingTime int32  `json:"reading_time"`
+	Attachments []attachmentInfo
+}
+
+// newRenderedPost renders a feed entry. Feeds carry only the excerpt of an
+// article, which is plain text rather than Markdown.
+func newRenderedPost(p db.GetLatestPostsRow) renderedPost {
+	html := renderMarkdown(p.Content)
+	if p.Kind == postArticle {
+		html = excerptHTML(p.Content)
+	}
+	return renderedPost{
+		GetLatestPostsRow: p,
+		HTML:              html,
+		Permalink:         permalink(p.Username, p.Slug),
+		Attachments:       feedAttachments(p.Attachments),
+		ReadingTime:       readingTime(p.WordCount),
+	}
+}
+
+type renderedComment struct {
+	db.GetLatestCommentsForPostRow
+	HTML string `json:"html"`
+}
@@ -0,0 +1,69 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownLRUEvictsBySize(t *testing.T) {
+	// Each entry takes 1 + 199 = 200 bytes, so five fill the cache.
+	c := newMarkdownLRU(1000)
+	// has looks an entry up without making it recently used.
+	has := func(key string) bool {
+		_, ok := c.entries[key]
+		return ok
+	}
+	html := strings.Repeat("x", 199)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.add(key, html)
+	}
+	if c.size != 1000 {
+		t.Fatalf("cache holds %d bytes, want 1000", c.size)
+	}
+
+	// Using a makes b the least recently used.
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	c.add("f", html)
+	if has("b") {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c", "d", "e", "f"} {
+		if !has(key) {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+
+	// A bigger entry evicts as many as it needs to: c and d, which are now
+	// the least recently used.
+	c.add("g", strings.Repeat("x", 249))
+	if c.size > 1000 {
+		t.Fatalf("cache holds %d bytes, more than 1000", c.size)
+	}
+	for _, key := range []string{"c", "d"} {
+		if has(key) {
+			t.Errorf("%s was not evicted", key)
+		}
+	}
+	for _, key := range []string{"a", "e", "f", "g"} {
+		if !has(key) {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestMarkdownLRUSkipsLargeDocuments(t *testing.T) {
+	c := newMarkdownLRU(400)
+	c.add("a", "small")
+	c.add("b", strings.Repeat("x", 100))
+	if _, ok := c.get("b"); ok {
+		t.Error("a document over a quarter of the cache was kept")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("a large document evicted a small one")
+	}
+	if c.size != len("a")+len("small") {
+		t.Errorf("cache holds %d bytes, want %d", c.size, len("a")+len("small"))
+	}
+}
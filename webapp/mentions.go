@@ -0,0 +1,207 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"encore.dev/types/uuid"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// maxMentions is how many users a single post or comment can mention.
+const maxMentions = 10
+
+var (
+	// A mention starts after whitespace or punctuation, so that email
+	// addresses and paths are not taken for one.
+	mentionPattern     = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@/\-])@([A-Za-z][A-Za-z0-9\-]*)`)
+	mentionHeadPattern = regexp.MustCompile(`^@([A-Za-z][A-Za-z0-9\-]*)`)
+)
+
+// mentionBoundary reports whether a mention can start after r.
+func mentionBoundary(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.@/-", r)
+}
+
+// isMentionName reports whether name could be a username.
+func isMentionName(name string) bool {
+	return len(name) >= 3 && len(name) <= 30
+}
+
+// extractMentions returns the distinct usernames mentioned in content, in
+// the order they first appear. Mentions in code are ignored.
+func extractMentions(content string) []string {
+	content = codePattern.ReplaceAllString(content, " ")
+
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := m[1]
+		if !isMentionName(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// recordMentions stores the users mentioned in a post, or in a comment on it
+// when commentID is set. Usernames that match no user are skipped, and
+// authors mentioning themselves are not recorded. The post or comment is
+// already saved, so failures are only logged.
+func recordMentions(ctx context.Context, authorID, postID uuid.UUID, commentID *uuid.UUID, content string) {
+	userIDs := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, name := range extractMentions(content) {
+		user, err := resolveUsername(ctx, name)
+		if err != nil {
+			if !isNotFound(err) {
+				println("Mention lookup error:", err.Error())
+			}
+			continue
+		}
+		if user.ID == authorID || seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+		userIDs = append(userIDs, user.ID)
+	}
+
+	if err := api.SetMentions(ctx, api.SetMentionsParams{
+		PostID:    postID,
+		CommentID: commentID,
+		UserIDs:   userIDs,
+	}); err != nil {
+		println("Mention error:", err.Error())
+	}
+}
+
+// mentionParser turns @username into a link to the user's profile. Whether
+// the user exists is not checked, so that rendering stays independent of
+// the database.
+type mentionParser struct{}
+
+func (mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if pc.IsInLinkLabel() {
+		return nil
+	}
+	if !mentionBoundary(block.PrecendingCharacter()) {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	m := mentionHeadPattern.FindSubmatchIndex(line)
+	if m == nil || !isMentionName(string(line[m[2]:m[3]])) {
+		return nil
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte("/@" + string(line[m[2]:m[3]]))
+	link.AppendChild(link, ast.NewTextSegment(segment.WithStop(segment.Start+m[1])))
+	block.Advance(m[1])
+	return link
+}
+
+// mentionExtension adds mention links to a goldmark renderer.
+type mentionExtension struct{}
+
+func (mentionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(mentionParser{}, 999),
+	))
+}
+
+//encore:api public raw path=/app/mentions
+func Mentions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Username string `json:"username"`
+		Limit    int32  `json:"limit"`
+		Offset   int32  `json:"offset"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := resolveUsername(r.Context(), req.Username)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	res, err := api.GetLatestMentionsForUser(r.Context(), db.GetLatestMentionsForUserParams{
+		UserID: user.ID,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	mentions := make([]map[string]interface{}, 0, len(res.Mentions))
+	for _, m := range res.Mentions {
+		kind, author := "post", m.PostAuthor
+		if m.CommentID != nil {
+			kind, author = "comment", ""
+			if m.CommentAuthor != nil {
+				author = *m.CommentAuthor
+			}
+		}
+		mentions = append(mentions, map[string]interface{}{
+			"id":         m.ID,
+			"kind":       kind,
+			"post_id":    m.PostID,
+			"comment_id": m.CommentID,
+			"author":     author,
+			"content":    m.Content,
+			"html":       renderMarkdown(m.Content),
+			"permalink":  permalink(m.PostAuthor, m.Slug),
+			"created_at": m.CreatedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": user.Username,
+		"mentions": mentions,
+	})
+}
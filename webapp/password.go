@@ -0,0 +1,302 @@
+package webapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"encore.dev/types/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// passwordHasher produces and checks one kind of stored password hash. The
+// encoded hash names its algorithm and parameters, so hashes made by
+// different hashers can live side by side in the users table.
+type passwordHasher interface {
+	// Hash returns the encoded hash of password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded.
+	Verify(encoded, password string) bool
+	// Handles reports whether encoded was produced by this kind of hasher.
+	Handles(encoded string) bool
+	// Outdated reports whether encoded should be replaced by a fresh Hash,
+	// e.g. because it was made with weaker parameters.
+	Outdated(encoded string) bool
+}
+
+// Site settings tuning argon2id for new hashes. Memory is in KiB. Existing
+// hashes keep verifying with the parameters encoded in them and are
+// upgraded on the next login once the settings change.
+const (
+	argon2MemoryKey      = "argon2_memory_kib"
+	argon2IterationsKey  = "argon2_iterations"
+	argon2ParallelismKey = "argon2_parallelism"
+)
+
+// Bounds on the argon2id parameters an admin may choose.
+const (
+	argon2MinMemory      = 8 * 1024
+	argon2MaxMemory      = 1024 * 1024
+	argon2MaxIterations  = 10
+	argon2MaxParallelism = 16
+)
+
+// defaultArgon2id hashes new passwords unless the site settings say
+// otherwise.
+var defaultArgon2id = argon2idHasher{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// passwordHashers are all kinds of hashes that are accepted. Each one
+// verifies with the parameters encoded in the hash itself.
+var passwordHashers = []passwordHasher{&argon2idHasher{}, bcryptHasher{}}
+
+// validArgon2id reports whether memory, iterations and parallelism are
+// within the bounds an admin may choose.
+func validArgon2id(memory, iterations, parallelism int) bool {
+	return memory >= argon2MinMemory && memory <= argon2MaxMemory &&
+		iterations >= 1 && iterations <= argon2MaxIterations &&
+		parallelism >= 1 && parallelism <= argon2MaxParallelism
+}
+
+// currentHasher returns the hasher for new passwords, as configured in the
+// site settings. Settings that cannot be read or are out of bounds fall
+// back to defaultArgon2id, so that logins keep working.
+func currentHasher(ctx context.Context) *argon2idHasher {
+	h := defaultArgon2id
+	memory, err := intSiteSetting(ctx, argon2MemoryKey, int(h.Memory))
+	if err != nil {
+		println("Password hashing settings error:", err.Error())
+		return &h
+	}
+	iterations, err := intSiteSetting(ctx, argon2IterationsKey, int(h.Iterations))
+	if err != nil {
+		println("Password hashing settings error:", err.Error())
+		return &h
+	}
+	parallelism, err := intSiteSetting(ctx, argon2ParallelismKey, int(h.Parallelism))
+	if err != nil {
+		println("Password hashing settings error:", err.Error())
+		return &h
+	}
+	if !validArgon2id(memory, iterations, parallelism) {
+		println("Ignoring invalid password hashing settings")
+		return &h
+	}
+
+	h.Memory = uint32(memory)
+	h.Iterations = uint32(iterations)
+	h.Parallelism = uint8(parallelism)
+	return &h
+}
+
+func hashPassword(ctx context.Context, password string) (string, error) {
+	return currentHasher(ctx).Hash(password)
+}
+
+// verifyPassword checks password against the stored hash encoded. When it
+// matches, rehash tells whether the stored hash should be upgraded.
+func verifyPassword(ctx context.Context, encoded, password string) (ok, rehash bool) {
+	for _, h := range passwordHashers {
+		if !h.Handles(encoded) {
+			continue
+		}
+		if !h.Verify(encoded, password) {
+			return false, false
+		}
+		current := currentHasher(ctx)
+		return true, !current.Handles(encoded) || current.Outdated(encoded)
+	}
+	return false, false
+}
+
+// argon2idHasher hashes with argon2id (RFC 9106) and encodes the result in
+// the PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$salt$key.
+type argon2idHasher struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(encoded, password string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (h *argon2idHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h *argon2idHasher) Outdated(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory != h.Memory ||
+		p.Iterations != h.Iterations ||
+		p.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+// decodeArgon2id splits a PHC string into its parameters, salt and key.
+func decodeArgon2id(encoded string) (p argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, err
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, err
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, err
+	}
+	return p, salt, key, nil
+}
+
+// bcryptHasher covers the hashes of accounts created before argon2id was
+// introduced. It is never used for new passwords.
+type bcryptHasher struct{}
+
+func (bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func (bcryptHasher) Verify(encoded, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+func (bcryptHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (bcryptHasher) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < bcrypt.DefaultCost
+}
+
+// rehashPassword replaces the stored hash of the user's password with one
+// made by currentHasher.
+func rehashPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hash, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	return api.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:           userID,
+		PasswordHash: hash,
+	})
+}
+
+//encore:api auth raw path=/app/admin/password-hashing
+func SetPasswordHashing(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		MemoryKiB   int `json:"memory_kib"`
+		Iterations  int `json:"iterations"`
+		Parallelism int `json:"parallelism"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if !validArgon2id(req.MemoryKiB, req.Iterations, req.Parallelism) {
+		http.Error(w, `{"error":"Memory must be between 8192 and 1048576 KiB, iterations between 1 and 10 and parallelism between 1 and 16"}`, http.StatusBadRequest)
+		return
+	}
+
+	for key, value := range map[string]int{
+		argon2MemoryKey:      req.MemoryKiB,
+		argon2IterationsKey:  req.Iterations,
+		argon2ParallelismKey: req.Parallelism,
+	} {
+		if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+			Key:   key,
+			Value: strconv.Itoa(value),
+		}); err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"memory_kib":  req.MemoryKiB,
+		"iterations":  req.Iterations,
+		"parallelism": req.Parallelism,
+	})
+}
@@ -0,0 +1,328 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Titles are optional and limited to maxTitleLength characters. Slugs are
+// cut to maxSlugLength characters, and a suffix is added after that when
+// the author already uses the slug.
+const (
+	maxTitleLength = 200
+	maxSlugLength  = 60
+	maxSlugSuffix  = 1000
+)
+
+// slugify turns text into a slug: lowercase letters and digits separated by
+// single dashes. Letters outside ASCII are kept. It returns "post" if the
+// text has no letters or digits.
+func slugify(text string) string {
+	var b strings.Builder
+	n := 0
+	dash := false
+	for _, c := range strings.ToLower(text) {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			dash = b.Len() > 0
+			continue
+		}
+		if n == maxSlugLength {
+			break
+		}
+		if dash {
+			if n+2 > maxSlugLength {
+				break
+			}
+			b.WriteByte('-')
+			n++
+			dash = false
+		}
+		b.WriteRune(c)
+		n++
+	}
+	if b.Len() == 0 {
+		return "post"
+	}
+	return b.String()
+}
+
+// uniqueSlug returns base, or base with the lowest numbered suffix that the
+// author does not use for a post other than postID.
+func uniqueSlug(ctx context.Context, userID, postID uuid.UUID, base string) (string, error) {
+	slug := base
+	for i := 2; i <= maxSlugSuffix; i++ {
+		res, err := api.IsPostSlugTaken(ctx, db.IsPostSlugTakenParams{
+			UserID: userID,
+			Slug:   slug,
+			PostID: postID,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !res.Taken {
+			return slug, nil
+		}
+		suffix := "-" + strconv.Itoa(i)
+		runes := []rune(base)
+		if len(runes)+len(suffix) > maxSlugLength {
+			runes = runes[:maxSlugLength-len(suffix)]
+		}
+		slug = strings.TrimRight(string(runes), "-") + suffix
+	}
+	return "", errors.New("no free slug for " + base)
+}
+
+// permalink is the path a post is served at.
+func permalink(username, slug string) string {
+	return "/@" + username + "/" + url.PathEscape(slug)
+}
+
+// resolvePermalink finds the post at a permalink, following old usernames
+// and old slugs. Deleted posts, and unpublished ones unless the caller wrote
+// them, are not found.
+func resolvePermalink(ctx context.Context, username, slug string) (*db.User, *db.Post, error) {
+	user, err := resolveUsername(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post, err := api.GetPostBySlug(ctx, db.GetPostBySlugParams{
+		UserID: user.ID,
+		Slug:   slug,
+	})
+	if err != nil && isNotFound(err) {
+		var res *api.GetPostIDBySlugHistoryResult
+		res, err = api.GetPostIDBySlugHistory(ctx, db.GetPostIDBySlugHistoryParams{
+			UserID: user.ID,
+			Slug:   slug,
+		})
+		if err == nil {
+			post, err = api.GetPostByID(ctx, res.PostID)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isDeleted(post.DeletedAt) {
+		return nil, nil, &errs.Error{Code: errs.NotFound, Message: "post is deleted"}
+	}
+	if !isVisible(post) {
+		return nil, nil, &errs.Error{Code: errs.NotFound, Message: "post is not published"}
+	}
+	return user, post, nil
+}
+
+// servePermalink redirects a permalink reached through an old username or
+// slug to the current one. It returns false if the request should be served
+// as usual.
+func servePermalink(w http.ResponseWriter, r *http.Request, requestPath string) bool {
+	rest, ok := strings.CutPrefix(requestPath, "/@")
+	if !ok {
+		return false
+	}
+	username, slug, ok := strings.Cut(rest, "/")
+	if !ok || username == "" || slug == "" || strings.Contains(slug, "/") {
+		return false
+	}
+
+	user, post, err := resolvePermalink(r.Context(), username, slug)
+	if err != nil {
+		if !isNotFound(err) {
+			println("Permalink lookup error:", err.Error())
+		}
+		return false
+	}
+
+	if user.Username == username && post.Slug == slug {
+		return false
+	}
+	http.Redirect(w, r, permalink(user.Username, post.Slug), http.StatusMovedPermanently)
+	return true
+}
+
+//encore:api public raw path=/app/permalink
+func Permalink(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Username string `json:"username"`
+		Slug     string `json:"slug"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, post, err := resolvePermalink(r.Context(), req.Username, req.Slug)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := api.GetPostTags(r.Context(), post.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	stored, err := api.GetPostAttachments(r.Context(), post.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	attachments := make([]attachmentInfo, 0, len(stored.Attachments))
+	for i := range stored.Attachments {
+		attachments = append(attachments, newAttachmentInfo(&stored.Attachments[i]))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           post.ID,
+		"username":     user.Username,
+		"tags":         tags.Tags,
+		"attachments":  attachments,
+		"title":        post.Title,
+		"slug":         post.Slug,
+		"permalink":    permalink(user.Username, post.Slug),
+		"redirected":   user.Username != req.Username || post.Slug != req.Slug,
+		"kind":         post.Kind,
+		"content":      post.Content,
+		"excerpt":      post.Excerpt,
+		"word_count":   post.WordCount,
+		"reading_time": readingTime(post.WordCount),
+		"html":         renderMarkdown(post.Content),
+		"created_at":   post.CreatedAt,
+		"edited":       post.UpdatedAt.After(post.PublishAt),
+		"status":       post.Status,
+		"publish_at":   post.PublishAt,
+	})
+}
+
+//encore:api auth raw path=/app/post/permalink
+func ChangePostPermalink(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID string `json:"post_id"`
+		Title  string `json:"title"`
+		Slug   string `json:"slug"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		http.Error(w, `{"error":"Title is too long"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	// Without a slug, the post keeps the one it has.
+	slug := ""
+	if req.Slug != "" {
+		slug = slugify(req.Slug)
+	} else {
+		post, err := api.GetPostByID(r.Context(), postID)
+		if err != nil {
+			if isNotFound(err) {
+				http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+				return
+			}
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+		slug = post.Slug
+	}
+
+	post, err := api.ChangePostPermalink(r.Context(), api.ChangePostPermalinkParams{
+		ID:     postID,
+		UserID: current.UserID,
+		Title:  title,
+		Slug:   slug,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		if errs.Code(err) == errs.PermissionDenied {
+			http.Error(w, `{"error":"Only the author can change a post's permalink"}`, http.StatusForbidden)
+			return
+		}
+		if errs.Code(err) == errs.AlreadyExists {
+			http.Error(w, `{"error":"Slug is already in use"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        post.ID,
+		"title":     post.Title,
+		"slug":      post.Slug,
+		"permalink": permalink(current.Username, post.Slug),
+	})
+}
@@ -0,0 +1,339 @@
+package webapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// What a proof-of-work challenge can be spent on. A challenge issued for
+// one purpose is not accepted for another.
+const (
+	powRegister = "register"
+	powPost     = "post"
+)
+
+// Site settings for proof of work. powDifficultyKey is the base number of
+// leading zero bits a solution needs, zero turning proof of work off.
+// powNewAccountPostsKey is how many of an account's first posts need one,
+// zero meaning none do.
+const (
+	powDifficultyKey      = "pow_difficulty"
+	powNewAccountPostsKey = "pow_new_account_posts"
+)
+
+// powDefaultDifficulty takes a browser well under a second to solve.
+// Whenever more than powLoadThreshold challenges for a purpose were solved
+// within powLoadWindow, and again each time that count doubles, one more
+// bit is required, up to powMaxLoadDifficulty. That takes a browser tens of
+// seconds at most, so real users are slowed down but not locked out. Only
+// an admin can go beyond it, up to powMaxDifficulty.
+const (
+	powDefaultDifficulty = 16
+	powMaxLoadDifficulty = 20
+	powMaxDifficulty     = 28
+	powLoadWindow        = 10 * time.Minute
+	powLoadThreshold     = 20
+	powChallengeTTL      = 10 * time.Minute
+	powMaxSolutionLength = 64
+)
+
+// intSiteSetting returns the integer stored under key, or def if the
+// setting is unset or unreadable.
+func intSiteSetting(ctx context.Context, key string, def int) (int, error) {
+	res, err := api.GetSiteSetting(ctx, key)
+	if err != nil {
+		if isNotFound(err) {
+			return def, nil
+		}
+		return 0, err
+	}
+	n, err := strconv.Atoi(res.Value)
+	if err != nil {
+		println("Ignoring invalid site setting:", key, res.Value)
+		return def, nil
+	}
+	return n, nil
+}
+
+// powEnabled reports whether proof of work is turned on at all.
+func powEnabled(ctx context.Context) (bool, error) {
+	difficulty, err := intSiteSetting(ctx, powDifficultyKey, powDefaultDifficulty)
+	return difficulty > 0, err
+}
+
+// powRequiredToPost reports whether the user's next post needs a proof of
+// work, which is the case while they have fewer posts than the site
+// setting asks for.
+func powRequiredToPost(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if enabled, err := powEnabled(ctx); err != nil || !enabled {
+		return false, err
+	}
+
+	posts, err := intSiteSetting(ctx, powNewAccountPostsKey, 0)
+	if err != nil || posts <= 0 {
+		return false, err
+	}
+
+	res, err := api.CountPostsByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return res.Count < int64(posts), nil
+}
+
+// powDifficulty returns how many leading zero bits a solution for purpose
+// currently needs, or zero if proof of work is off.
+func powDifficulty(ctx context.Context, purpose string) (int, error) {
+	difficulty, err := intSiteSetting(ctx, powDifficultyKey, powDefaultDifficulty)
+	if err != nil || difficulty <= 0 {
+		return 0, err
+	}
+
+	res, err := api.CountChallengesSpentSince(ctx, db.CountChallengesSpentSinceParams{
+		Purpose: purpose,
+		Since:   time.Now().Add(-powLoadWindow),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for n := res.Count; n > powLoadThreshold && difficulty < powMaxLoadDifficulty; n /= 2 {
+		difficulty++
+	}
+
+	if difficulty > powMaxDifficulty {
+		difficulty = powMaxDifficulty
+	}
+	return difficulty, nil
+}
+
+// powSignature authenticates everything in a challenge that comes before
+// it, so the server does not have to remember the challenges it hands out.
+func powSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(secrets.SessionSecret))
+	mac.Write([]byte("pow:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newChallenge returns a signed challenge of the form
+// purpose.nonce.difficulty.expiry.signature.
+func newChallenge(purpose string, difficulty int, expiresAt time.Time) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	payload := strings.Join([]string{
+		purpose,
+		hex.EncodeToString(b),
+		strconv.Itoa(difficulty),
+		strconv.FormatInt(expiresAt.Unix(), 10),
+	}, ".")
+	return payload + "." + powSignature(payload), nil
+}
+
+// powSolved reports whether SHA-256 of challenge, a colon and solution
+// starts with at least difficulty zero bits.
+func powSolved(challenge, solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+	zeros := 0
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros >= difficulty
+}
+
+// requireProofOfWork checks that challenge was issued for purpose, has not
+// expired, is solved by solution and has not been spent before, and then
+// spends it. On rejection it writes a structured error and returns false.
+func requireProofOfWork(w http.ResponseWriter, r *http.Request, purpose, challenge, solution string) bool {
+	if challenge == "" || solution == "" {
+		http.Error(w, `{"error":"Proof of work is required","code":"pow_required"}`, http.StatusForbidden)
+		return false
+	}
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 5 || parts[0] != purpose || len(solution) > powMaxSolutionLength {
+		http.Error(w, `{"error":"Proof of work is invalid","code":"pow_invalid"}`, http.StatusForbidden)
+		return false
+	}
+
+	payload := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(powSignature(payload))) {
+		http.Error(w, `{"error":"Proof of work is invalid","code":"pow_invalid"}`, http.StatusForbidden)
+		return false
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.Error(w, `{"error":"Proof of work is invalid","code":"pow_invalid"}`, http.StatusForbidden)
+		return false
+	}
+
+	expiry, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Proof of work is invalid","code":"pow_invalid"}`, http.StatusForbidden)
+		return false
+	}
+	expiresAt := time.Unix(expiry, 0)
+
+	if time.Now().After(expiresAt) {
+		http.Error(w, `{"error":"Proof of work challenge has expired","code":"pow_expired"}`, http.StatusForbidden)
+		return false
+	}
+
+	if !powSolved(challenge, solution, difficulty) {
+		http.Error(w, `{"error":"Proof of work is invalid","code":"pow_invalid"}`, http.StatusForbidden)
+		return false
+	}
+
+	res, err := api.SpendChallenge(r.Context(), db.SpendChallengeParams{
+		Nonce:     parts[1],
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return false
+	}
+
+	if !res.Spent {
+		http.Error(w, `{"error":"Proof of work challenge was already used","code":"pow_reused"}`, http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+//encore:api public raw path=/app/auth/challenge
+func Challenge(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	purpose := r.URL.Query().Get("purpose")
+	if purpose != powRegister && purpose != powPost {
+		http.Error(w, `{"error":"Unknown challenge purpose"}`, http.StatusBadRequest)
+		return
+	}
+
+	difficulty, err := powDifficulty(r.Context(), purpose)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if difficulty == 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"required": false,
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(powChallengeTTL)
+	challenge, err := newChallenge(purpose, difficulty, expiresAt)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create challenge"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"required":   true,
+		"algorithm":  "sha256",
+		"challenge":  challenge,
+		"difficulty": difficulty,
+		"expires_at": expiresAt,
+	})
+}
+
+//encore:api auth raw path=/app/admin/pow
+func SetProofOfWork(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Difficulty      int `json:"difficulty"`
+		NewAccountPosts int `json:"new_account_posts"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if req.Difficulty < 0 || req.Difficulty > powMaxDifficulty {
+		http.Error(w, `{"error":"Difficulty must be between 0 and 28"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.NewAccountPosts < 0 || req.NewAccountPosts > 100 {
+		http.Error(w, `{"error":"New account posts must be between 0 and 100"}`, http.StatusBadRequest)
+		return
+	}
+
+	for key, value := range map[string]int{
+		powDifficultyKey:      req.Difficulty,
+		powNewAccountPostsKey: req.NewAccountPosts,
+	} {
+		if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+			Key:   key,
+			Value: strconv.Itoa(value),
+		}); err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":           true,
+		"difficulty":        req.Difficulty,
+		"new_account_posts": req.NewAccountPosts,
+	})
+}
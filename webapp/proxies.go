@@ -0,0 +1,171 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// trustedProxiesKey is the site setting listing the reverse proxies whose
+// X-Forwarded-For header is believed, as addresses or CIDR ranges separated
+// by spaces. Unless it is set, the header is ignored.
+const trustedProxiesKey = "trusted_proxies"
+
+// maxTrustedProxies bounds how many entries the setting may hold.
+const maxTrustedProxies = 32
+
+// parseProxies turns a list of addresses and CIDR ranges into networks. A
+// plain address stands for itself alone.
+func parseProxies(list []string) ([]*net.IPNet, bool) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, entry := range list {
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, false
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, true
+}
+
+// trustedProxies returns the networks configured as trusted proxies. An
+// unreadable setting trusts nobody.
+func trustedProxies(ctx context.Context) []*net.IPNet {
+	res, err := api.GetSiteSetting(ctx, trustedProxiesKey)
+	if err != nil {
+		if !isNotFound(err) {
+			println("Trusted proxies error:", err.Error())
+		}
+		return nil
+	}
+	nets, ok := parseProxies(strings.Fields(res.Value))
+	if !ok {
+		println("Ignoring invalid site setting:", trustedProxiesKey, res.Value)
+		return nil
+	}
+	return nets
+}
+
+func isTrusted(nets []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that sent r. X-Forwarded-For
+// is only believed when the connection comes from a trusted proxy, and then
+// only as far back as the hops were added by trusted proxies too: the
+// nearest hop that is not one is the client.
+func clientIP(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+
+	fwd := r.Header.Values("X-Forwarded-For")
+	if len(fwd) == 0 {
+		return addr
+	}
+
+	nets := trustedProxies(r.Context())
+	if !isTrusted(nets, addr) {
+		return addr
+	}
+
+	hops := strings.Split(strings.Join(fwd, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		addr = hop
+		if !isTrusted(nets, hop) {
+			break
+		}
+	}
+	return addr
+}
+
+//encore:api auth raw path=/app/admin/proxies
+func SetTrustedProxies(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Proxies []string `json:"proxies"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if len(req.Proxies) > maxTrustedProxies {
+		http.Error(w, `{"error":"At most 32 trusted proxies can be configured"}`, http.StatusBadRequest)
+		return
+	}
+
+	nets, ok := parseProxies(req.Proxies)
+	if !ok {
+		http.Error(w, `{"error":"Proxies must be IP addresses or CIDR ranges"}`, http.StatusBadRequest)
+		return
+	}
+
+	proxies := make([]string, 0, len(nets))
+	for _, n := range nets {
+		proxies = append(proxies, n.String())
+	}
+
+	if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+		Key:   trustedProxiesKey,
+		Value: strings.Join(proxies, " "),
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"proxies": proxies,
+	})
+}
@@ -0,0 +1,409 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// reactionEmojiKey is the site setting listing the emoji people can react
+// with, separated by spaces, in the order pickers show them.
+const reactionEmojiKey = "reaction_emoji"
+
+// defaultReactionEmoji are the emoji people can react with unless the site
+// setting lists others.
+var defaultReactionEmoji = []string{"👍", "❤️", "😂", "😮", "😢", "🎉"}
+
+// maxReactionEmoji bounds how many emoji the allowlist may hold, and
+// maxEmojiRunes how long each may be; sequences such as 👩‍👩‍👧 take several
+// code points.
+const (
+	maxReactionEmoji = 32
+	maxEmojiRunes    = 8
+)
+
+// maxReactionsPage is the most reactions listed at once.
+const maxReactionsPage = 100
+
+// variationSelector asks for an emoji to be drawn in color, as in ❤️.
+const variationSelector = "\uFE0F"
+
+// reactionEmoji returns the allowlist, in the order pickers show it.
+func reactionEmoji(ctx context.Context) ([]string, error) {
+	res, err := api.GetSiteSetting(ctx, reactionEmojiKey)
+	if err != nil {
+		if isNotFound(err) {
+			return defaultReactionEmoji, nil
+		}
+		return nil, err
+	}
+	list := strings.Fields(res.Value)
+	if len(list) == 0 {
+		return defaultReactionEmoji, nil
+	}
+	return list, nil
+}
+
+// validEmoji reports whether s looks like a single emoji: a few code points,
+// not all ASCII, with no letters, spaces or control characters.
+func validEmoji(s string) bool {
+	if !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxEmojiRunes {
+		return false
+	}
+	ascii := true
+	for _, c := range s {
+		if unicode.IsLetter(c) || unicode.IsSpace(c) || unicode.IsControl(c) {
+			return false
+		}
+		if c >= utf8.RuneSelf {
+			ascii = false
+		}
+	}
+	return !ascii
+}
+
+// allowedEmoji returns the form emoji has in list. Variation selectors are
+// ignored when comparing, since clients disagree on whether to send them.
+func allowedEmoji(list []string, emoji string) (string, bool) {
+	bare := strings.ReplaceAll(emoji, variationSelector, "")
+	for _, e := range list {
+		if strings.ReplaceAll(e, variationSelector, "") == bare {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// reactionCount is how many users reacted with an emoji, in the same shape
+// as the reactions of feed entries.
+type reactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int64  `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// reactionTarget resolves the post, or the comment on it, that a reaction
+// request is about. Both have to be live and visible to the viewer. On
+// failure it writes the error and returns false.
+func reactionTarget(w http.ResponseWriter, r *http.Request, postID, commentID string) (*db.Post, *uuid.UUID, bool) {
+	id, err := uuid.FromString(postID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	post, err := api.GetPostByID(r.Context(), id)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if err != nil || isDeleted(post.DeletedAt) || !isVisible(post) {
+		http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if commentID == "" {
+		return post, nil, true
+	}
+
+	cid, err := uuid.FromString(commentID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid comment ID"}`, http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	comment, err := api.GetCommentByID(r.Context(), cid)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if err != nil || isDeleted(comment.DeletedAt) || comment.PostID != post.ID {
+		http.Error(w, `{"error":"Comment not found"}`, http.StatusNotFound)
+		return nil, nil, false
+	}
+	return post, &cid, true
+}
+
+//encore:api public raw path=/app/reactions/emoji
+func ReactionEmoji(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	list, err := reactionEmoji(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"emoji": list,
+	})
+}
+
+//encore:api auth raw path=/app/reactions/toggle
+func ToggleReaction(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID    string `json:"post_id"`
+		CommentID string `json:"comment_id"`
+		Emoji     string `json:"emoji"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	list, err := reactionEmoji(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Emoji dropped from the allowlist cannot be added any more, but
+	// reactions already made with them can still be taken back.
+	emoji, allowed := allowedEmoji(list, req.Emoji)
+	if !allowed {
+		if !validEmoji(req.Emoji) {
+			http.Error(w, `{"error":"Emoji is not allowed"}`, http.StatusBadRequest)
+			return
+		}
+		emoji = req.Emoji
+	}
+
+	current := authData()
+	if !current.HasScope(scopeReactionWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	post, commentID, ok := reactionTarget(w, r, req.PostID, req.CommentID)
+	if !ok {
+		return
+	}
+
+	if post.Status != postPublished {
+		http.Error(w, `{"error":"Only published posts can be reacted to"}`, http.StatusConflict)
+		return
+	}
+
+	res, err := api.ToggleReaction(r.Context(), api.ToggleReactionParams{
+		PostID:     post.ID,
+		CommentID:  commentID,
+		UserID:     current.UserID,
+		Emoji:      emoji,
+		RemoveOnly: !allowed,
+	})
+	if err != nil {
+		if errs.Code(err) == errs.FailedPrecondition {
+			http.Error(w, `{"error":"Emoji is not allowed"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	target := post.ID
+	if commentID != nil {
+		target = *commentID
+	}
+	counts, err := api.GetReactionCounts(r.Context(), db.GetReactionCountsParams{
+		ViewerID: current.UserID,
+		TargetID: target,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	reactions := make([]reactionCount, 0, len(counts.Counts))
+	for _, c := range counts.Counts {
+		reactions = append(reactions, reactionCount(c))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"emoji":     emoji,
+		"reacted":   res.Reacted,
+		"reactions": reactions,
+	})
+}
+
+//encore:api public raw path=/app/reactions
+func Reactions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		PostID    string `json:"post_id"`
+		CommentID string `json:"comment_id"`
+		Limit     int32  `json:"limit"`
+		Offset    int32  `json:"offset"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Limit <= 0 || req.Limit > maxReactionsPage {
+		req.Limit = maxReactionsPage
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	post, commentID, ok := reactionTarget(w, r, req.PostID, req.CommentID)
+	if !ok {
+		return
+	}
+
+	target := post.ID
+	if commentID != nil {
+		target = *commentID
+	}
+	res, err := api.GetReactionsForTarget(r.Context(), db.GetReactionsForTargetParams{
+		TargetID: target,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	reactions := make([]map[string]interface{}, 0, len(res.Reactions))
+	for _, rc := range res.Reactions {
+		reactions = append(reactions, map[string]interface{}{
+			"username":   rc.Username,
+			"emoji":      rc.Emoji,
+			"created_at": rc.CreatedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reactions": reactions,
+	})
+}
+
+//encore:api auth raw path=/app/admin/reactions/emoji
+func SetReactionEmoji(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Emoji []string `json:"emoji"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	// An empty list goes back to the default set.
+	if len(req.Emoji) > maxReactionEmoji {
+		http.Error(w, `{"error":"At most 32 emoji can be allowed"}`, http.StatusBadRequest)
+		return
+	}
+
+	list := make([]string, 0, len(req.Emoji))
+	for _, e := range req.Emoji {
+		e = strings.TrimSpace(e)
+		if !validEmoji(e) {
+			http.Error(w, `{"error":"Each entry must be a single emoji"}`, http.StatusBadRequest)
+			return
+		}
+		if _, dup := allowedEmoji(list, e); dup {
+			http.Error(w, `{"error":"Emoji are listed twice"}`, http.StatusBadRequest)
+			return
+		}
+		list = append(list, e)
+	}
+
+	if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+		Key:   reactionEmojiKey,
+		Value: strings.Join(list, " "),
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(list) == 0 {
+		list = defaultReactionEmoji
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"emoji":   list,
+	})
+}
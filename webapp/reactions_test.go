@@ -0,0 +1,98 @@
+package webapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"encore.dev/beta/errs"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+func TestAllowedEmojiIgnoresVariationSelectors(t *testing.T) {
+	list := []string{"👍", "❤️"}
+	for _, in := range []string{"❤", "❤️"} {
+		if got, ok := allowedEmoji(list, in); !ok || got != "❤️" {
+			t.Errorf("allowedEmoji(%q) = %q, %v; want %q", in, got, ok, "❤️")
+		}
+	}
+	if _, ok := allowedEmoji(list, "🎉"); ok {
+		t.Error("emoji outside the list is allowed")
+	}
+}
+
+func TestValidEmoji(t *testing.T) {
+	for _, s := range []string{"👍", "❤️", "👩‍👩‍👧"} {
+		if !validEmoji(s) {
+			t.Errorf("validEmoji(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "a", ":)", "👍 👍", "👍x", "👍👍👍👍👍👍👍👍👍"} {
+		if validEmoji(s) {
+			t.Errorf("validEmoji(%q) = true", s)
+		}
+	}
+}
+
+func TestToggleReactionRemoveOnly(t *testing.T) {
+	requireDatabase(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, ctx)
+	post, err := api.CreatePost(ctx, db.CreatePostParams{
+		UserID:    user.ID,
+		Content:   "Reactions",
+		Slug:      "reactions",
+		Status:    postPublished,
+		PublishAt: time.Now(),
+		Kind:      postNote,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	toggle := func(emoji string, removeOnly bool) (bool, error) {
+		res, err := api.ToggleReaction(ctx, api.ToggleReactionParams{
+			PostID:     post.ID,
+			UserID:     user.ID,
+			Emoji:      emoji,
+			RemoveOnly: removeOnly,
+		})
+		if err != nil {
+			return false, err
+		}
+		return res.Reacted, nil
+	}
+
+	// An emoji no longer allowed cannot be added.
+	if _, err := toggle("🎉", true); errs.Code(err) != errs.FailedPrecondition {
+		t.Fatalf("adding a disallowed emoji: got %v, want FailedPrecondition", err)
+	}
+
+	// But a reaction made while it was allowed can be taken back.
+	if reacted, err := toggle("🎉", false); err != nil || !reacted {
+		t.Fatalf("adding: reacted %v, err %v", reacted, err)
+	}
+	if reacted, err := toggle("🎉", true); err != nil || reacted {
+		t.Fatalf("removing: reacted %v, err %v", reacted, err)
+	}
+
+	// A reaction stored without the variation selector is found with it.
+	if reacted, err := toggle("❤", false); err != nil || !reacted {
+		t.Fatalf("adding: reacted %v, err %v", reacted, err)
+	}
+	if reacted, err := toggle("❤️", false); err != nil || reacted {
+		t.Fatalf("removing: reacted %v, err %v", reacted, err)
+	}
+	counts, err := api.GetReactionCounts(ctx, db.GetReactionCountsParams{
+		ViewerID: user.ID,
+		TargetID: post.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts.Counts) != 0 {
+		t.Fatalf("reactions left behind: %+v", counts.Counts)
+	}
+}
@@ -0,0 +1,347 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+)
+
+// postVersion is one version of a post as shown to readers. The latest
+// version is the post itself.
+type postVersion struct {
+	Revision  int32     `json:"revision"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"`
+}
+
+// postVersions returns every version of a post, oldest first.
+func postVersions(ctx context.Context, postID uuid.UUID) ([]postVersion, error) {
+	post, err := api.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if isDeleted(post.DeletedAt) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "post is deleted"}
+	}
+	if !isVisible(post) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "post is not published"}
+	}
+
+	res, err := api.GetPostRevisions(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]postVersion, 0, len(res.Revisions)+1)
+	for _, rev := range res.Revisions {
+		versions = append(versions, postVersion{
+			Revision:  rev.Revision,
+			Content:   rev.Content,
+			CreatedAt: rev.CreatedAt,
+		})
+	}
+	versions = append(versions, postVersion{
+		Revision:  int32(len(res.Revisions) + 1),
+		Content:   post.Content,
+		CreatedAt: post.UpdatedAt,
+		Current:   true,
+	})
+	return versions, nil
+}
+
+// diffOp is one step of a diff: text that is kept, deleted or inserted.
+type diffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diffs are computed word by word, or line by line when that would take
+// more than diffMaxCells steps.
+const diffMaxCells = 1 << 20
+
+var (
+	diffWordPattern = regexp.MustCompile(`\s+|\S+`)
+	diffLinePattern = regexp.MustCompile(`[^\n]*\n|[^\n]+`)
+)
+
+// diffText returns the steps that turn a into b, merging consecutive steps
+// of the same kind.
+func diffText(a, b string) []diffOp {
+	x := diffWordPattern.FindAllString(a, -1)
+	y := diffWordPattern.FindAllString(b, -1)
+	if len(x)*len(y) > diffMaxCells {
+		x = diffLinePattern.FindAllString(a, -1)
+		y = diffLinePattern.FindAllString(b, -1)
+	}
+	if len(x)*len(y) > diffMaxCells {
+		return []diffOp{{Op: "delete", Text: a}, {Op: "insert", Text: b}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0)
+	add := func(op, text string) {
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text += text
+			return
+		}
+		ops = append(ops, diffOp{Op: op, Text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			add("equal", x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add("delete", x[i])
+			i++
+		default:
+			add("insert", y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		add("delete", x[i])
+	}
+	for ; j < len(y); j++ {
+		add("insert", y[j])
+	}
+	return ops
+}
+
+//encore:api auth raw path=/app/post/edit
+func EditPost(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID  string `json:"post_id"`
+		Content string `json:"content"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	// The limit depends on the kind of post, which cannot change.
+	existing, err := api.GetPostByID(r.Context(), postID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !checkContent(w, existing.Kind, req.Content) {
+		return
+	}
+
+	attachments, ok := checkAttachments(w, r.Context(), current.UserID, req.Content)
+	if !ok {
+		return
+	}
+
+	excerpt, words := summarize(req.Content)
+
+	post, err := api.EditPost(r.Context(), api.EditPostParams{
+		ID:        postID,
+		UserID:    current.UserID,
+		Content:   req.Content,
+		Excerpt:   excerpt,
+		WordCount: words,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		if errs.Code(err) == errs.PermissionDenied {
+			http.Error(w, `{"error":"Only the author can edit a post"}`, http.StatusForbidden)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tagPost(r.Context(), post.ID, post.Content)
+	attachPost(r.Context(), post.ID, attachments)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         post.ID,
+		"content":    post.Content,
+		"excerpt":    post.Excerpt,
+		"word_count": post.WordCount,
+		"updated_at": post.UpdatedAt,
+		"edited":     post.UpdatedAt.After(post.PublishAt),
+	})
+}
+
+//encore:api public raw path=/app/post/revisions
+func PostRevisions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		PostID string `json:"post_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	versions, err := postVersions(r.Context(), postID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"revisions": versions,
+	})
+}
+
+//encore:api public raw path=/app/post/diff
+func PostDiff(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		PostID string `json:"post_id"`
+		From   int32  `json:"from"`
+		To     int32  `json:"to"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	postID, err := uuid.FromString(req.PostID)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid post ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	versions, err := postVersions(r.Context(), postID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Without revision numbers, show what the latest edit changed.
+	if req.To == 0 {
+		req.To = int32(len(versions))
+	}
+	if req.From == 0 {
+		req.From = max(req.To-1, 1)
+	}
+
+	if req.From < 1 || req.To < 1 || int(req.From) > len(versions) || int(req.To) > len(versions) {
+		http.Error(w, `{"error":"Revision not found"}`, http.StatusNotFound)
+		return
+	}
+
+	from := versions[req.From-1]
+	to := versions[req.To-1]
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"diff": diffText(from.Content, to.Content),
+	})
+}
@@ -0,0 +1,244 @@
+package webapp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Roles a user can hold. Every account is a user; the others are granted
+// explicitly and are ordered by privilege, so an admin may do anything a
+// moderator may.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var grantableRoles = []string{roleModerator, roleAdmin}
+
+func isGrantableRole(role string) bool {
+	for _, r := range grantableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the caller holds role or one that outranks it.
+func (d *AuthData) HasRole(role string) bool {
+	for _, r := range d.Roles {
+		if r == role || r == roleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// requireRole lets the request through only if the caller holds role,
+// writing a 403 otherwise. Privileged actions are never available to
+// personal access tokens, whatever their scopes.
+func requireRole(w http.ResponseWriter, current *AuthData, role string) bool {
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return false
+	}
+	if !current.HasRole(role) {
+		http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+//encore:api auth raw path=/app/admin/roles
+func RoleAssignments(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	res, err := api.GetRoleAssignments(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	assignments := make([]map[string]interface{}, 0, len(res.Assignments))
+	for _, a := range res.Assignments {
+		assignments = append(assignments, map[string]interface{}{
+			"user_id":    a.UserID,
+			"username":   a.Username,
+			"role":       a.Role,
+			"granted_at": a.GrantedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"assignments": assignments,
+	})
+}
+
+//encore:api auth raw path=/app/admin/roles/grant
+func GrantRole(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if !isGrantableRole(req.Role) {
+		http.Error(w, `{"error":"Unknown role"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := api.GetUserByUsername(r.Context(), req.Username)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	res, err := api.GrantUserRole(r.Context(), db.GrantUserRoleParams{
+		UserID: user.ID,
+		Role:   req.Role,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if res.Granted {
+		audit(r, auditRoleGrant, &user.ID, user.Username, auditSuccess, req.Role+" granted by "+authData().Username)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"granted": res.Granted,
+	})
+}
+
+//encore:api auth raw path=/app/admin/roles/revoke
+func RevokeRole(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !requireRole(w, current, roleAdmin) {
+		return
+	}
+
+	if !isGrantableRole(req.Role) {
+		http.Error(w, `{"error":"Unknown role"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := api.GetUserByUsername(r.Context(), req.Username)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// An admin stepping down would otherwise be able to leave the site
+	// without any admin at all.
+	if user.ID == current.UserID && req.Role == roleAdmin {
+		http.Error(w, `{"error":"You cannot revoke your own admin role"}`, http.StatusBadRequest)
+		return
+	}
+
+	res, err := api.RevokeUserRole(r.Context(), db.RevokeUserRoleParams{
+		UserID: user.ID,
+		Role:   req.Role,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !res.Revoked {
+		http.Error(w, `{"error":"User does not hold this role"}`, http.StatusNotFound)
+		return
+	}
+
+	audit(r, auditRoleRevoke, &user.ID, user.Username, auditSuccess, req.Role+" revoked by "+current.Username)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
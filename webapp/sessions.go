@@ -0,0 +1,262 @@
+package webapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/storage/sqldb"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// sessionMaxAge is the lifetime of a session in seconds, both for the
+// cookie and for the server-side record it points to.
+const sessionMaxAge = 86400 * 7
+
+// pendingTwoFactorMaxAge bounds how long a login that passed the password
+// check may wait for its second factor.
+const pendingTwoFactorMaxAge = 5 * time.Minute
+
+var errNoSession = errors.New("no active session")
+
+// isNotFound reports whether err means the requested row does not exist,
+// whether it came straight from the database or through an API call.
+func isNotFound(err error) bool {
+	return errors.Is(err, sqldb.ErrNoRows) || errs.Code(err) == errs.NotFound
+}
+
+// startSession records a new server-side session for user and points the
+// markblog cookie at it. A session that still awaits its second factor is
+// short-lived and is not accepted by AuthHandler.
+func startSession(w http.ResponseWriter, r *http.Request, user *db.User, twoFactorPending bool) error {
+	// A cookie that fails to decode (e.g. signed with an old secret) is
+	// simply replaced, so the error from Get is not fatal here.
+	session, _ := store.Get(r, "markblog")
+
+	ip := truncate(clientIP(r), 45)
+
+	expiresAt := time.Now().Add(sessionMaxAge * time.Second)
+	if twoFactorPending {
+		expiresAt = time.Now().Add(pendingTwoFactorMaxAge)
+	}
+
+	s, err := api.CreateSession(r.Context(), db.CreateSessionParams{
+		UserID:           user.ID,
+		UserAgent:        r.UserAgent(),
+		IpAddress:        ip,
+		ExpiresAt:        expiresAt,
+		TwoFactorPending: twoFactorPending,
+	})
+	if err != nil {
+		return err
+	}
+
+	session.Values["authenticated"] = !twoFactorPending
+	session.Values["session_id"] = s.ID.String()
+	session.Values["user_id"] = user.ID.String()
+	session.Values["username"] = user.Username
+	session.Options.MaxAge = sessionMaxAge
+
+	return session.Save(r, w)
+}
+
+// pendingSession returns the caller's session that passed the password
+// check but still awaits its second factor, or errNoSession. Such sessions
+// are invisible to AuthHandler, so this is the one place besides it that
+// reads the cookie.
+func pendingSession(r *http.Request) (*db.Session, error) {
+	cookie, err := r.Cookie("markblog")
+	if err != nil {
+		return nil, errNoSession
+	}
+	id, ok := sessionIDFromCookie(cookie.Value)
+	if !ok {
+		return nil, errNoSession
+	}
+
+	s, err := api.GetActiveSessionByID(r.Context(), id)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errNoSession
+		}
+		return nil, err
+	}
+	if !s.TwoFactorPending {
+		return nil, errNoSession
+	}
+	return s, nil
+}
+
+// endSession expires the markblog cookie in the caller's browser.
+func endSession(w http.ResponseWriter, r *http.Request) error {
+	session, _ := store.Get(r, "markblog")
+	session.Values["authenticated"] = false
+	session.Values["session_id"] = ""
+	session.Values["user_id"] = ""
+	session.Values["username"] = ""
+	session.Options.MaxAge = -1 // Immediately expire the session
+
+	return session.Save(r, w)
+}
+
+//encore:api auth raw path=/app/auth/sessions
+func Sessions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.GetActiveSessionsForUser(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sessions := make([]map[string]interface{}, 0, len(res.Sessions))
+	for _, s := range res.Sessions {
+		sessions = append(sessions, map[string]interface{}{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip_address":   s.IpAddress,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == *current.SessionID,
+			// A session still awaiting its second factor is only visible
+			// here so that it can be revoked like any other.
+			"two_factor_pending": s.TwoFactorPending,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+//encore:api auth raw path=/app/auth/sessions/revoke
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.DeleteSessionForUser(r.Context(), db.DeleteSessionForUserParams{
+		ID:     req.ID,
+		UserID: current.UserID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if res.Deleted == 0 {
+		http.Error(w, `{"error":"Session not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if req.ID == *current.SessionID {
+		if err := endSession(w, r); err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   "Failed to save session",
+			})
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+//encore:api auth raw path=/app/auth/sessions/revoke-others
+func RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.DeleteOtherSessionsForUser(r.Context(), db.DeleteOtherSessionsForUserParams{
+		UserID: current.UserID,
+		ID:     *current.SessionID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"revoked": res.Deleted,
+	})
+}
@@ -0,0 +1,274 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"encore.dev"
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// A post keeps at most maxPostTags tags, each at most maxTagLength
+// characters long.
+const (
+	maxPostTags  = 10
+	maxTagLength = 50
+)
+
+// Trending tags are counted over the last defaultTrendingWindow unless the
+// caller asks for another window of up to maxTrendingWindow.
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTagListLimit   = 10
+	maxTagListLimit       = 50
+)
+
+var (
+	// A hashtag starts after whitespace or punctuation, so that URL
+	// fragments and HTML entities are not taken for one.
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]+)`)
+	tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+	codePattern    = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// normalizeTag returns the stored form of a tag: lowercase and without the
+// leading #. It returns "" for anything that is not a valid tag, including
+// tags made of digits only.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if !tagNamePattern.MatchString(tag) || utf8.RuneCountInString(tag) > maxTagLength {
+		return ""
+	}
+	if strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return tag
+}
+
+// extractTags returns the distinct hashtags in a post, in the order they
+// first appear. Hashtags in code are ignored.
+func extractTags(content string) []string {
+	content = codePattern.ReplaceAllString(content, " ")
+
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := normalizeTag(m[1])
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxPostTags {
+			break
+		}
+	}
+	return tags
+}
+
+// tagPost stores the hashtags found in content as the tags of a post. The
+// post itself is already saved, so failures are only logged.
+func tagPost(ctx context.Context, postID uuid.UUID, content string) {
+	if err := api.SetPostTags(ctx, api.SetPostTagsParams{
+		PostID: postID,
+		Tags:   extractTags(content),
+	}); err != nil {
+		println("Tagging error:", err.Error())
+	}
+}
+
+// likePrefix returns a LIKE pattern matching strings that start with s.
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+//encore:api public raw path=/app/tags/:tag
+func TagFeed(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	tag := normalizeTag(encore.CurrentRequest().PathParams.Get("tag"))
+	if tag == "" {
+		http.Error(w, `{"error":"Invalid tag"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Offset int32 `json:"offset"`
+		Limit  int32 `json:"limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	posts, err := api.GetLatestPostsByTag(r.Context(), db.GetLatestPostsByTagParams{
+		Tag:      tag,
+		ViewerID: viewerID(),
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rendered := make([]renderedPost, 0, len(posts.Posts))
+	for _, p := range posts.Posts {
+		rendered = append(rendered, newRenderedPost(db.GetLatestPostsRow(p)))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tag":   tag,
+		"posts": rendered,
+	})
+}
+
+//encore:api public raw path=/app/trending/tags
+func TrendingTags(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Hours int32 `json:"hours"`
+		Limit int32 `json:"limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	window := defaultTrendingWindow
+	if req.Hours != 0 {
+		window = time.Duration(req.Hours) * time.Hour
+	}
+	if window < time.Hour || window > maxTrendingWindow {
+		http.Error(w, `{"error":"Window must be between 1 and 168 hours"}`, http.StatusBadRequest)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > maxTagListLimit {
+		limit = defaultTagListLimit
+	}
+
+	res, err := api.GetTrendingTags(r.Context(), db.GetTrendingTagsParams{
+		PublishAt: time.Now().Add(-window),
+		Limit:     limit,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tags := make([]map[string]interface{}, 0, len(res.Tags))
+	for _, t := range res.Tags {
+		tags = append(tags, map[string]interface{}{
+			"tag":   t.Name,
+			"posts": t.Posts,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hours": int(window / time.Hour),
+		"tags":  tags,
+	})
+}
+
+//encore:api public raw path=/app/autocomplete/tags
+func TagAutocomplete(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Prefix string `json:"prefix"`
+		Limit  int32  `json:"limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Digits alone are a valid start of a tag, so only the characters are
+	// checked here.
+	prefix := strings.ToLower(strings.TrimPrefix(req.Prefix, "#"))
+	if !tagNamePattern.MatchString(prefix) || utf8.RuneCountInString(prefix) > maxTagLength {
+		http.Error(w, `{"error":"Invalid prefix"}`, http.StatusBadRequest)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > maxTagListLimit {
+		limit = defaultTagListLimit
+	}
+
+	res, err := api.SearchTagsByPrefix(r.Context(), db.SearchTagsByPrefixParams{
+		Name:  likePrefix(prefix),
+		Limit: limit,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tags := make([]map[string]interface{}, 0, len(res.Tags))
+	for _, t := range res.Tags {
+		tags = append(tags, map[string]interface{}{
+			"tag":   t.Name,
+			"posts": t.Posts,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": tags,
+	})
+}
@@ -0,0 +1,258 @@
+package webapp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// Scopes a personal access token can be granted. Tokens carry them as a
+// space-separated list.
+const (
+	scopeRead          = "read"
+	scopePostWrite     = "post:write"
+	scopeCommentWrite  = "comment:write"
+	scopeReactionWrite = "reaction:write"
+)
+
+var tokenScopes = []string{scopeRead, scopePostWrite, scopeCommentWrite, scopeReactionWrite}
+
+// accessTokenPrefix makes tokens recognizable, e.g. to secret scanners.
+const accessTokenPrefix = "mbp_"
+
+// newAccessToken returns a fresh token, the prefix shown to the user to tell
+// tokens apart, and the hash that is stored in its place.
+func newAccessToken() (token, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = accessTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, token[:len(accessTokenPrefix)+6], hashAccessToken(token), nil
+}
+
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseBearer extracts the token from an "Authorization: Bearer" header.
+func parseBearer(h string) (string, bool) {
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+func isTokenScope(scope string) bool {
+	for _, s := range tokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//encore:api auth raw path=/app/auth/tokens
+func AccessTokens(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.GetAccessTokensForUser(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tokens := make([]map[string]interface{}, 0, len(res.Tokens))
+	for _, t := range res.Tokens {
+		token := map[string]interface{}{
+			"id":         t.ID,
+			"name":       t.Name,
+			"prefix":     t.TokenPrefix,
+			"scopes":     strings.Fields(t.Scopes),
+			"created_at": t.CreatedAt,
+		}
+		if t.LastUsedAt.Unix() > 0 {
+			token["last_used_at"] = t.LastUsedAt
+		}
+		tokens = append(tokens, token)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+//encore:api auth raw path=/app/auth/tokens/create
+func CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		http.Error(w, `{"error":"Name length is invalid"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		http.Error(w, `{"error":"At least one scope is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	granted := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool)
+	for _, s := range req.Scopes {
+		if !isTokenScope(s) {
+			http.Error(w, `{"error":"Unknown scope"}`, http.StatusBadRequest)
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			granted = append(granted, s)
+		}
+	}
+
+	// Tokens can only be minted from a browser session, never by another
+	// token.
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	token, prefix, hash, err := newAccessToken()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	t, err := api.CreateAccessToken(r.Context(), db.CreateAccessTokenParams{
+		UserID:      current.UserID,
+		Name:        name,
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+		Scopes:      strings.Join(granted, " "),
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// This is the only time the token itself is ever shown.
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     t.ID,
+		"name":   t.Name,
+		"scopes": granted,
+		"token":  token,
+	})
+}
+
+//encore:api auth raw path=/app/auth/tokens/revoke
+func RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.DeleteAccessTokenForUser(r.Context(), db.DeleteAccessTokenForUserParams{
+		ID:     req.ID,
+		UserID: current.UserID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !res.Deleted {
+		http.Error(w, `{"error":"Token not found"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
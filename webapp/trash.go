@@ -0,0 +1,454 @@
+package webapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// trashRetentionKey is the site setting the api service reads when purging
+// deleted posts and comments, and defaultTrashRetentionDays what it falls
+// back on.
+const (
+	trashRetentionKey         = "trash_retention_days"
+	defaultTrashRetentionDays = 30
+)
+
+// isDeleted reports whether a deleted_at column marks its row as deleted.
+// Live rows hold the Unix epoch.
+func isDeleted(deletedAt time.Time) bool {
+	return deletedAt.After(time.Unix(0, 0))
+}
+
+// canModerate reports whether the caller may delete and restore content
+// written by others.
+func canModerate(current *AuthData) bool {
+	return current.SessionID != nil && current.HasRole(roleModerator)
+}
+
+// canRestore reports whether the caller may restore content written by
+// authorID and deleted by deletedBy. Authors may only undo their own
+// deletions, not those of a moderator.
+func canRestore(current *AuthData, authorID uuid.UUID, deletedBy *uuid.UUID) bool {
+	if canModerate(current) {
+		return true
+	}
+	return authorID == current.UserID && deletedBy != nil && *deletedBy == current.UserID
+}
+
+//encore:api auth raw path=/app/post/delete
+func DeletePost(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID uuid.UUID `json:"post_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	post, err := api.GetPostByID(r.Context(), req.PostID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if isDeleted(post.DeletedAt) {
+		http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if post.UserID != current.UserID && !canModerate(current) {
+		http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.SoftDeletePost(r.Context(), db.SoftDeletePostParams{
+		ID:        post.ID,
+		DeletedBy: &current.UserID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"deleted": res.Deleted,
+	})
+}
+
+//encore:api auth raw path=/app/post/restore
+func RestorePost(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		PostID uuid.UUID `json:"post_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	post, err := api.GetPostByID(r.Context(), req.PostID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !isDeleted(post.DeletedAt) {
+		http.Error(w, `{"error":"Post is not deleted"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !canRestore(current, post.UserID, post.DeletedBy) {
+		http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.RestorePost(r.Context(), post.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"restored": res.Restored,
+	})
+}
+
+//encore:api auth raw path=/app/comment/delete
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		CommentID uuid.UUID `json:"comment_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopeCommentWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	comment, err := api.GetCommentByID(r.Context(), req.CommentID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Comment not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if isDeleted(comment.DeletedAt) {
+		http.Error(w, `{"error":"Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	isAuthor := comment.UserID != nil && *comment.UserID == current.UserID
+	if !isAuthor && !canModerate(current) {
+		http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.SoftDeleteComment(r.Context(), db.SoftDeleteCommentParams{
+		ID:        comment.ID,
+		DeletedBy: &current.UserID,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"deleted": res.Deleted,
+	})
+}
+
+//encore:api auth raw path=/app/comment/restore
+func RestoreComment(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		CommentID uuid.UUID `json:"comment_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if !current.HasScope(scopeCommentWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	comment, err := api.GetCommentByID(r.Context(), req.CommentID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Comment not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !isDeleted(comment.DeletedAt) {
+		http.Error(w, `{"error":"Comment is not deleted"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Comments kept from deleted accounts have no author left to restore
+	// them.
+	var authorID uuid.UUID
+	if comment.UserID != nil {
+		authorID = *comment.UserID
+	}
+
+	if !canRestore(current, authorID, comment.DeletedBy) {
+		http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+		return
+	}
+
+	res, err := api.RestoreComment(r.Context(), comment.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"restored": res.Restored,
+	})
+}
+
+//encore:api auth raw path=/app/trash
+func Trash(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+	if !current.HasScope(scopeRead) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	retention, err := intSiteSetting(r.Context(), trashRetentionKey, defaultTrashRetentionDays)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	deletedPosts, err := api.GetDeletedPostsByUser(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	deletedComments, err := api.GetDeletedCommentsByUser(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	posts := make([]map[string]interface{}, 0, len(deletedPosts.Posts))
+	for _, p := range deletedPosts.Posts {
+		posts = append(posts, map[string]interface{}{
+			"id":         p.ID,
+			"content":    p.Content,
+			"created_at": p.CreatedAt,
+			"deleted_at": p.DeletedAt,
+			"purge_at":   p.DeletedAt.AddDate(0, 0, retention),
+			"restorable": canRestore(current, p.UserID, p.DeletedBy),
+		})
+	}
+
+	comments := make([]map[string]interface{}, 0, len(deletedComments.Comments))
+	for _, c := range deletedComments.Comments {
+		comments = append(comments, map[string]interface{}{
+			"id":         c.ID,
+			"post_id":    c.PostID,
+			"content":    c.Content,
+			"created_at": c.CreatedAt,
+			"deleted_at": c.DeletedAt,
+			"purge_at":   c.DeletedAt.AddDate(0, 0, retention),
+			"restorable": canRestore(current, current.UserID, c.DeletedBy),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"posts":    posts,
+		"comments": comments,
+	})
+}
+
+//encore:api auth raw path=/app/admin/trash/retention
+func SetTrashRetention(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Days int `json:"days"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, authData(), roleAdmin) {
+		return
+	}
+
+	if req.Days < 1 || req.Days > 3650 {
+		http.Error(w, `{"error":"Retention must be between 1 and 3650 days"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.UpsertSiteSetting(r.Context(), db.UpsertSiteSettingParams{
+		Key:   trashRetentionKey,
+		Value: strconv.Itoa(req.Days),
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"days":    req.Days,
+	})
+}
@@ -0,0 +1,416 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// verifySecondFactor checks either a TOTP code or a recovery code for the
+// user. Both are single-use: the TOTP time step is burned and the recovery
+// code is deleted, so neither can be replayed.
+func verifySecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		res, err := api.ConsumeRecoveryCode(ctx, db.ConsumeRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			return false, err
+		}
+		return res.Consumed, nil
+	}
+
+	totp, err := api.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !totp.Enabled {
+		return false, nil
+	}
+
+	step, ok := totpMatch(totp.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	res, err := api.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Accepted, nil
+}
+
+//encore:api public raw path=/app/auth/login/2fa
+func LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		http.Error(w, `{"error":"Code is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	pending, err := pendingSession(r)
+	if err != nil {
+		if errors.Is(err, errNoSession) {
+			http.Error(w, `{"error":"No login is awaiting a second factor"}`, http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ok, err := verifySecondFactor(r.Context(), pending.UserID, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The pending session is spent either way: on failure so that codes
+	// cannot be guessed without repeating the password step, on success
+	// because it is replaced by a fresh, fully authenticated one.
+	if err := api.DeleteSession(r.Context(), pending.ID); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		audit(r, auditLoginTwoFactor, &pending.UserID, "", auditFailure, "invalid code")
+		http.Error(w, `{"error":"Invalid code"}`, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), pending.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := startSession(w, r, user, false); err != nil {
+		println("Session start error:", err.Error())
+		audit(r, auditSession, &user.ID, user.Username, auditError, err.Error())
+		http.Error(w, `{"error":"Failed to save session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	detail := ""
+	if req.Code == "" {
+		detail = "recovery code"
+	}
+	audit(r, auditLoginTwoFactor, &user.ID, user.Username, auditSuccess, detail)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"user": map[string]interface{}{
+			"id":       user.ID,
+			"username": user.Username,
+		},
+	})
+}
+
+//encore:api auth raw path=/app/auth/2fa/enroll
+func TwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	existing, err := api.GetUserTOTP(r.Context(), current.UserID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if err == nil && existing.Enabled {
+		http.Error(w, `{"error":"Two-factor authentication is already enabled"}`, http.StatusConflict)
+		return
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate secret"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := api.UpsertUserTOTP(r.Context(), db.UpsertUserTOTPParams{
+		UserID: current.UserID,
+		Secret: secret,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret": secret,
+		"uri":    totpURI(current.Username, secret),
+	})
+}
+
+//encore:api auth raw path=/app/auth/2fa/activate
+func TwoFactorActivate(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	totp, err := api.GetUserTOTP(r.Context(), current.UserID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"Enrollment has not been started"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if totp.Enabled {
+		http.Error(w, `{"error":"Two-factor authentication is already enabled"}`, http.StatusConflict)
+		return
+	}
+
+	step, ok := totpMatch(totp.Secret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, `{"error":"Invalid code"}`, http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate recovery codes"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := api.EnableUserTOTP(r.Context(), api.EnableUserTOTPParams{
+		UserID:             current.UserID,
+		LastUsedStep:       step,
+		RecoveryCodeHashes: hashes,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, auditTwoFactorEnable, &current.UserID, current.Username, auditSuccess, "")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"recovery_codes": codes,
+	})
+}
+
+//encore:api auth raw path=/app/auth/2fa/recovery-codes
+func TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	ok, err := verifySecondFactor(r.Context(), current.UserID, req.Code, "")
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"Invalid code"}`, http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to generate recovery codes"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := api.ReplaceRecoveryCodes(r.Context(), api.ReplaceRecoveryCodesParams{
+		UserID:     current.UserID,
+		CodeHashes: hashes,
+	}); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"recovery_codes": codes,
+	})
+}
+
+//encore:api auth raw path=/app/auth/2fa/disable
+func TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if ok, _ := verifyPassword(r.Context(), user.PasswordHash, req.Password); !ok {
+		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ok, err := verifySecondFactor(r.Context(), user.ID, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"Invalid code"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if err := api.DisableUserTOTP(r.Context(), user.ID); err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, auditTwoFactorDisable, &user.ID, user.Username, auditSuccess, "")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
@@ -0,0 +1,239 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"encore.dev/types/uuid"
+
+	"encore.app/api"
+	"encore.app/api/db"
+)
+
+// usernameChangeCooldown is how long a user has to wait between renames.
+// usernameHoldPeriod is how long a username given up in a rename stays
+// reserved for the account that gave it up.
+const (
+	usernameChangeCooldown = 30 * 24 * time.Hour
+	usernameHoldPeriod     = 90 * 24 * time.Hour
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9\-]*$`)
+
+// validateUsername checks username against the rules every account name
+// has to follow. On rejection it writes the error and returns false.
+func validateUsername(w http.ResponseWriter, username string) bool {
+	if !usernamePattern.MatchString(username) {
+		http.Error(w, `{"error":"Username has illegal characters"}`, http.StatusBadRequest)
+		return false
+	}
+
+	if len(username) < 3 || len(username) > 30 {
+		http.Error(w, `{"error":"Username length must be between 3 and 30"}`, http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// usernameHeld reports whether username was recently given up by an
+// account other than userID, which may be nil for someone registering.
+func usernameHeld(ctx context.Context, username string, userID *uuid.UUID) (bool, error) {
+	res, err := api.GetPreviousUsernameOwner(ctx, db.GetPreviousUsernameOwnerParams{
+		OldUsername: username,
+		Since:       time.Now().Add(-usernameHoldPeriod),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return userID == nil || res.UserID != *userID, nil
+}
+
+// resolveUsername returns the user currently known as username or, failing
+// that, the one who most recently went by it before a rename.
+func resolveUsername(ctx context.Context, username string) (*db.User, error) {
+	user, err := api.GetUserByUsername(ctx, username)
+	if err == nil || !isNotFound(err) {
+		return user, err
+	}
+
+	res, err := api.GetPreviousUsernameOwner(ctx, db.GetPreviousUsernameOwnerParams{
+		OldUsername: username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return api.GetUserByID(ctx, res.UserID)
+}
+
+//encore:api auth raw path=/app/auth/username
+func ChangeUsername(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Username == "" || req.Password == "" {
+		http.Error(w, `{"error":"Username and password are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !validateUsername(w, req.Username) {
+		return
+	}
+
+	current := authData()
+	if current.SessionID == nil {
+		http.Error(w, `{"error":"A browser session is required"}`, http.StatusForbidden)
+		return
+	}
+
+	user, err := api.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if ok, _ := verifyPassword(r.Context(), user.PasswordHash, req.Password); !ok {
+		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if req.Username == user.Username {
+		http.Error(w, `{"error":"This is already your username"}`, http.StatusBadRequest)
+		return
+	}
+
+	last, err := api.GetLastUsernameChange(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if next := last.ChangedAt.Add(usernameChangeCooldown); time.Now().Before(next) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":          "Username was changed too recently",
+			"next_change_at": next,
+		})
+		return
+	}
+
+	exists, err := api.CheckUserExists(r.Context(), req.Username)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if exists.Exists {
+		http.Error(w, `{"error":"Username already taken"}`, http.StatusConflict)
+		return
+	}
+
+	held, err := usernameHeld(r.Context(), req.Username, &user.ID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if held {
+		http.Error(w, `{"error":"Username was recently used by another account"}`, http.StatusConflict)
+		return
+	}
+
+	renamed, err := api.ChangeUsername(r.Context(), db.UpdateUsernameParams{
+		ID:       user.ID,
+		Username: req.Username,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	audit(r, auditUsernameChange, &user.ID, renamed.Username, auditSuccess, "from "+user.Username)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"user": map[string]interface{}{
+			"id":       renamed.ID,
+			"username": renamed.Username,
+		},
+		"next_change_at": time.Now().Add(usernameChangeCooldown),
+	})
+}
+
+//encore:api auth raw path=/app/auth/username/history
+func UsernameHistory(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	current := authData()
+
+	res, err := api.GetUsernameHistory(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	history := make([]map[string]interface{}, 0, len(res.History))
+	for _, h := range res.History {
+		history = append(history, map[string]interface{}{
+			"old_username": h.OldUsername,
+			"new_username": h.NewUsername,
+			"changed_at":   h.ChangedAt,
+		})
+	}
+
+	var nextChangeAt interface{}
+	if len(res.History) > 0 {
+		nextChangeAt = res.History[0].ChangedAt.Add(usernameChangeCooldown)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username":       current.Username,
+		"history":        history,
+		"next_change_at": nextChangeAt,
+	})
+}
@@ -7,13 +7,15 @@ import (
 	"io/fs"
 	"net/http"
 	"path"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"encore.dev/beta/errs"
 	"encore.dev/storage/sqldb"
 	"encore.dev/types/uuid"
 	"github.com/gorilla/sessions"
-	"golang.org/x/crypto/bcrypt"
 
 	"encore.app/api"
 	"encore.app/api/db"
@@ -21,6 +23,16 @@ import (
 
 var secrets struct {
 	SessionSecret string
+
+	// SMTPServer is the host:port of the mail relay. Leave it empty to
+	// print mail to the log instead of sending it.
+	SMTPServer   string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	// PublicURL is where users reach the app. It is used for links in mail
+	// and is allowed as a request origin alongside the local dev server.
+	PublicURL string
 }
 
 var store = sessions.NewCookieStore([]byte(secrets.SessionSecret))
@@ -31,7 +43,7 @@ func InitService() {
 	}
 	store.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400 * 7,
+		MaxAge:   sessionMaxAge,
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteLaxMode,
@@ -62,6 +74,9 @@ func Serve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	requestPath := path.Clean(r.URL.Path)
+	if servePermalink(w, r, requestPath) {
+		return
+	}
 	filePath := strings.TrimPrefix(requestPath, "/")
 	if filePath == "" {
 		filePath = "index.html"
@@ -85,13 +100,20 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
 	}
 	w.Header().Set("Content-Type", "application/json")
 
+	if !csrfProtect(w, r) {
+		return
+	}
+
 	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		InviteCode   string `json:"invite_code"`
+		PowChallenge string `json:"pow_challenge"`
+		PowSolution  string `json:"pow_solution"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
@@ -100,14 +122,19 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	username := req.Username
 	password := req.Password
+	inviteCode := strings.TrimSpace(req.InviteCode)
 
 	if username == "" || password == "" {
 		http.Error(w, `{"error":"Username and password are required"}`, http.StatusBadRequest)
 		return
 	}
 
-	if !regexp.MustCompile(`^[A-Za-z][A-Za-z0-9\-]*$`).MatchString(username) {
-		http.Error(w, `{"error":"Username has illegal characters"}`, http.StatusBadRequest)
+	if !validateUsername(w, username) {
+		return
+	}
+
+	if len(password) < 8 {
+		http.Error(w, `{"error":"Password must be at least 8 characters"}`, http.StatusBadRequest)
 		return
 	}
 
@@ -116,13 +143,32 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(username) < 3 || len(username) > 30 {
-		http.Error(w, `{"error":"Username length must be between 3 and 30"}`, http.StatusBadRequest)
+	// Proof of work comes before anything is looked up, so that the answers
+	// below, such as whether a username is taken, cost as much to get as
+	// an account does.
+	powOn, err := powEnabled(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	if len(password) < 8 {
-		http.Error(w, `{"error":"Password must be at least 8 characters"}`, http.StatusBadRequest)
+	if powOn && !requireProofOfWork(w, r, powRegister, req.PowChallenge, req.PowSolution) {
+		return
+	}
+
+	policy, err := registrationPolicy(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if policy == registrationClosed {
+		http.Error(w, `{"error":"Registration is closed"}`, http.StatusForbidden)
+		return
+	}
+
+	if policy == registrationInviteOnly && inviteCode == "" {
+		http.Error(w, `{"error":"An invite code is required"}`, http.StatusForbidden)
 		return
 	}
 
@@ -138,35 +184,51 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	held, err := usernameHeld(r.Context(), username, nil)
 	if err != nil {
-		http.Error(w, `{"error":"Failed to secure password"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	user, err := api.CreateUser(r.Context(), db.CreateUserParams{
-		Username:     username,
-		PasswordHash: string(hashedPassword),
-	})
+	if held {
+		http.Error(w, `{"error":"Username was recently used by another account"}`, http.StatusConflict)
+		return
+	}
 
+	hashedPassword, err := hashPassword(r.Context(), password)
 	if err != nil {
-		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error":"Failed to secure password"}`, http.StatusInternalServerError)
 		return
 	}
 
-	session, err := store.Get(r, "markblog")
+	var user *db.User
+	if inviteCode != "" {
+		user, err = api.CreateInvitedUser(r.Context(), api.CreateInvitedUserParams{
+			Username:     username,
+			PasswordHash: hashedPassword,
+			InviteCode:   inviteCode,
+		})
+	} else {
+		user, err = api.CreateUser(r.Context(), db.CreateUserParams{
+			Username:     username,
+			PasswordHash: hashedPassword,
+		})
+	}
+
 	if err != nil {
-		println("Session get error:", err.Error())
-		http.Error(w, `{"error":"Session error"}`, http.StatusInternalServerError)
+		if errs.Code(err) == errs.FailedPrecondition {
+			http.Error(w, `{"error":"Invalid or expired invite code"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	session.Values["authenticated"] = true
-	session.Values["user_id"] = user.ID.String()
-	session.Values["username"] = user.Username
+	audit(r, auditRegister, &user.ID, user.Username, auditSuccess, "")
 
-	if err := session.Save(r, w); err != nil {
-		println("Session save error:", err.Error())
+	if err := startSession(w, r, user, false); err != nil {
+		println("Session start error:", err.Error())
+		audit(r, auditSession, &user.ID, user.Username, auditError, err.Error())
 		http.Error(w, `{"error":"Failed to save session"}`, http.StatusInternalServerError)
 		return
 	}
@@ -187,10 +249,14 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
 	}
 	w.Header().Set("Content-Type", "application/json")
 
+	if !csrfProtect(w, r) {
+		return
+	}
+
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
@@ -208,50 +274,84 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	exists, err := api.CheckUserExists(r.Context(), username)
-	
-	if err != nil && !errors.Is(err, sqldb.ErrNoRows) {
+	ip := clientIP(r)
+
+	lockedUntil, err := loginLockedUntil(r.Context(), username, ip)
+	if err != nil {
 		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
-	
-	if !exists.Exists {
-		http.Error(w, `{"error":"User does not exist"}`, http.StatusNotFound)
+
+	if !lockedUntil.IsZero() {
+		audit(r, auditLogin, nil, username, auditFailure, "locked out")
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+		http.Error(w, `{"error":"Too many failed attempts, try again later"}`, http.StatusTooManyRequests)
 		return
 	}
 
 	user, err := api.GetUserByUsername(r.Context(), username)
-	if err != nil {
-		if errors.Is(err, sqldb.ErrNoRows) {
-			http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
-			return
-		}
+	if err != nil && !isNotFound(err) {
 		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	// An unknown user and a wrong password must look the same from the
+	// outside, down to the time it takes to answer.
+	passwordHash := dummyPasswordHash(r.Context())
+	if err == nil {
+		passwordHash = user.PasswordHash
+	}
+	ok, rehash := verifyPassword(r.Context(), passwordHash, password)
+	if !ok || err != nil {
+		if err := recordLoginFailure(r.Context(), username, ip); err != nil {
+			println("Login failure record error:", err.Error())
+		}
+		var userID *uuid.UUID
+		if err == nil {
+			userID = &user.ID
+		}
+		audit(r, auditLogin, userID, username, auditFailure, "invalid credentials")
 		http.Error(w, `{"error":"Invalid credentials"}`, http.StatusUnauthorized)
 		return
 	}
 
-	session, err := store.Get(r, "markblog")
-	if err != nil {
-		println("Session get error:", err.Error())
-		http.Error(w, `{"error":"Session error"}`, http.StatusInternalServerError)
-		return
+	if err := clearLoginFailures(r.Context(), username); err != nil {
+		println("Login failure clear error:", err.Error())
+	}
+
+	// Hashes made with an older algorithm or weaker parameters are upgraded
+	// while the plain password is at hand. Failing to do so is not fatal.
+	if rehash {
+		if err := rehashPassword(r.Context(), user.ID, password); err != nil {
+			println("Password rehash error:", err.Error())
+		}
 	}
 
-	session.Values["authenticated"] = true
-	session.Values["user_id"] = user.ID.String()
-	session.Values["username"] = user.Username
+	totp, err := api.GetUserTOTP(r.Context(), user.ID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	twoFactorRequired := err == nil && totp.Enabled
 
-	if err := session.Save(r, w); err != nil {
-		println("Session save error:", err.Error())
+	if err := startSession(w, r, user, twoFactorRequired); err != nil {
+		println("Session start error:", err.Error())
+		audit(r, auditSession, &user.ID, user.Username, auditError, err.Error())
 		http.Error(w, `{"error":"Failed to save session"}`, http.StatusInternalServerError)
 		return
 	}
 
+	if twoFactorRequired {
+		audit(r, auditLogin, &user.ID, user.Username, auditSuccess, "awaiting second factor")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":             true,
+			"two_factor_required": true,
+		})
+		return
+	}
+
+	audit(r, auditLogin, &user.ID, user.Username, auditSuccess, "")
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"user": map[string]interface{}{
@@ -268,7 +368,7 @@ func CheckAuth(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	}
 
 	if r.Method == "OPTIONS" {
@@ -278,62 +378,54 @@ func CheckAuth(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	session, err := store.Get(r, "markblog")
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated": false,
-			"error":         "Session error",
-		})
+	if current := authData(); current != nil && current.HasScope(scopeRead) {
+		res := map[string]interface{}{
+			"authenticated": true,
+			"user": map[string]interface{}{
+				"id":       current.UserID,
+				"username": current.Username,
+				"roles":    current.Roles,
+			},
+		}
+		if current.SessionID != nil {
+			res["session_id"] = *current.SessionID
+			res["csrf_token"] = csrfToken(*current.SessionID)
+		} else {
+			res["token_id"] = *current.TokenID
+		}
+		json.NewEncoder(w).Encode(res)
 		return
 	}
 
-	authenticated, ok := session.Values["authenticated"].(bool)
-	userID := session.Values["user_id"].(string)
-	username := session.Values["username"].(string)
-	
-	if !ok || !authenticated {
+	if _, err := pendingSession(r); err == nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated": false,
+			"authenticated":       false,
+			"two_factor_required": true,
 		})
 		return
 	}
-	
-	exists, err := api.CheckUserExists(r.Context(), username)
-	
-	if err != nil && !errors.Is(err, sqldb.ErrNoRows) || !exists.Exists{
-		session.Values["authenticated"] = false
-		session.Values["user_id"] = ""
-		session.Values["username"] = ""
-		session.Options.MaxAge = -1 // Immediately expire the session
-
-		if err := session.Save(r, w); err != nil {
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Failed to save session",
-			})
-			return
-		}
-		http.Error(w, `{"error":"User outdated"}`, http.StatusConflict)
+
+	if err := endSession(w, r); err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Failed to save session",
+		})
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"authenticated": true,
-		"user": map[string]interface{}{
-			"id":       userID,
-			"username": username,
-		},
+		"authenticated": false,
 	})
 }
 
-//encore:api public raw path=/app/auth/logout
+//encore:api auth raw path=/app/auth/logout
 func Logout(w http.ResponseWriter, r *http.Request) {
 	origin := r.Header.Get("Origin")
 	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
 	}
 
 	if r.Method == "OPTIONS" {
@@ -343,21 +435,21 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	session, err := store.Get(r, "markblog")
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Session error",
-		})
+	if !csrfProtect(w, r) {
 		return
 	}
 
-	session.Values["authenticated"] = false
-	session.Values["user_id"] = ""
-	session.Values["username"] = ""
-	session.Options.MaxAge = -1 // Immediately expire the session
+	current := authData()
+	if current.SessionID != nil {
+		if err := api.DeleteSession(r.Context(), *current.SessionID); err != nil {
+			http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	audit(r, auditLogout, &current.UserID, current.Username, auditSuccess, "")
 
-	if err := session.Save(r, w); err != nil {
+	if err := endSession(w, r); err != nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "Failed to save session",
@@ -370,14 +462,14 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-//encore:api public raw path=/app/post
+//encore:api auth raw path=/app/post
 func Post(w http.ResponseWriter, r *http.Request) {
 	origin := r.Header.Get("Origin")
 	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
 	}
 	
 	if r.Method == "OPTIONS" {
@@ -386,9 +478,20 @@ func Post(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
 	
 	var req struct {
-		Content string `json:"content"`
+		Content      string `json:"content"`
+		Kind         string    `json:"kind"`
+		Title        string    `json:"title"`
+		Slug         string    `json:"slug"`
+		Status       string    `json:"status"`
+		PublishAt    time.Time `json:"publish_at"`
+		PowChallenge string    `json:"pow_challenge"`
+		PowSolution  string `json:"pow_solution"`
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -398,32 +501,105 @@ func Post(w http.ResponseWriter, r *http.Request) {
 
 	content := req.Content
 
-	if content == "" || len(content) > 300{
-		http.Error(w, `{"error":"Content length is invalid}`, http.StatusBadRequest)
+	kind := req.Kind
+	if kind == "" {
+		kind = postNote
+	}
+	if kind != postNote && kind != postArticle {
+		http.Error(w, `{"error":"Invalid post kind"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !checkContent(w, kind, content) {
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		http.Error(w, `{"error":"Title is too long"}`, http.StatusBadRequest)
+		return
+	}
+	if kind == postArticle && title == "" {
+		http.Error(w, `{"error":"Articles need a title"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Posts are published right away unless saved as a draft or scheduled.
+	status := req.Status
+	if status == "" {
+		status = postPublished
+	}
+	publishAt, ok := publication(w, status, req.PublishAt)
+	if !ok {
 		return
 	}
 	
-	session, err := store.Get(r, "markblog")
+	current := authData()
+	if !current.HasScope(scopePostWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+		return
+	}
+
+	powNeeded, err := powRequiredToPost(r.Context(), current.UserID)
 	if err != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Session error",
-		})
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if powNeeded && !requireProofOfWork(w, r, powPost, req.PowChallenge, req.PowSolution) {
 		return
 	}
 	
+	// The slug comes from the first of slug, title and content given.
+	source := req.Slug
+	if source == "" {
+		source = title
+	}
+	if source == "" {
+		source = content
+	}
+
+	attachments, ok := checkAttachments(w, r.Context(), current.UserID, content)
+	if !ok {
+		return
+	}
+
+	slug, err := uniqueSlug(r.Context(), current.UserID, uuid.Nil, slugify(source))
+	if err != nil {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+	
+	excerpt, words := summarize(content)
+
 	post, err := api.CreatePost(r.Context(), db.CreatePostParams{
-		UserID: uuid.Must(uuid.FromString(session.Values["user_id"].(string))),
+		UserID: current.UserID,
 		Content: content,
+		Title: title,
+		Slug: slug,
+		Status: status,
+		PublishAt: publishAt,
+		Kind: kind,
+		Excerpt: excerpt,
+		WordCount: words,
 	})
 	
 	if err != nil {
 		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
+
+	tagPost(r.Context(), post.ID, post.Content)
+	attachPost(r.Context(), post.ID, attachments)
+	recordMentions(r.Context(), current.UserID, post.ID, nil, post.Content)
 	
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"id": post.ID,
+		"kind": post.Kind,
+		"slug": post.Slug,
+		"permalink": permalink(current.Username, post.Slug),
+		"status": post.Status,
+		"publish_at": post.PublishAt,
 	})
 }
 
@@ -458,6 +634,7 @@ func Feed(w http.ResponseWriter, r *http.Request) {
 	limit := req.Limit
 	
 	posts, err := api.GetLatestPosts(r.Context(), db.GetLatestPostsParams{
+		ViewerID: viewerID(),
 		Offset: offset,
 		Limit: limit,
 	})
@@ -467,8 +644,13 @@ func Feed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	rendered := make([]renderedPost, 0, len(posts.Posts))
+	for _, p := range posts.Posts {
+		rendered = append(rendered, newRenderedPost(p))
+	}
+	
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"posts": posts.Posts,
+		"posts": rendered,
 	})
 }
 
@@ -505,6 +687,7 @@ func Discussion(w http.ResponseWriter, r *http.Request) {
 	offset := req.Offset
 	
 	comments, err := api.GetLatestCommentsForPost(r.Context(), db.GetLatestCommentsForPostParams{
+		ViewerID: viewerID(),
 		PostID: id,
 		Limit: limit,
 		Offset: offset,
@@ -515,19 +698,24 @@ func Discussion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	rendered := make([]renderedComment, 0, len(comments.Comments))
+	for _, c := range comments.Comments {
+		rendered = append(rendered, renderedComment{c, renderMarkdown(c.Content)})
+	}
+	
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"comments": comments.Comments,
+		"comments": rendered,
 	})
 }
 
-//encore:api public raw path=/app/comment
+//encore:api auth raw path=/app/comment
 func Comment(w http.ResponseWriter, r *http.Request) {
 	origin := r.Header.Get("Origin")
 	if origin == "http://127.0.0.1:4000" || origin == "http://localhost:4000" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
 	}
 	
 	if r.Method == "OPTIONS" {
@@ -536,6 +724,10 @@ func Comment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+
+	if !csrfProtect(w, r) {
+		return
+	}
 	
 	var req struct {
 		PostID uuid.UUID `json:"post_id"`
@@ -550,21 +742,34 @@ func Comment(w http.ResponseWriter, r *http.Request) {
 	postID := req.PostID
 	content:= req.Content
 	
-	if content == "" || len(content) > 128{
-		http.Error(w, `{"error":"Content length is invalid}`, http.StatusBadRequest)
+	if content == "" || utf8.RuneCountInString(content) > maxCommentLength {
+		http.Error(w, `{"error":"Content length is invalid"}`, http.StatusBadRequest)
 		return
 	}
 	
-	session, err := store.Get(r, "markblog")
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Session error",
-		})
+	current := authData()
+	if !current.HasScope(scopeCommentWrite) {
+		http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
 		return
 	}
 	
-	userID := uuid.Must(uuid.FromString(session.Values["user_id"].(string)))
+	userID := current.UserID
+
+	post, err := api.GetPostByID(r.Context(), postID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err != nil || isDeleted(post.DeletedAt) || !isVisible(post) {
+		http.Error(w, `{"error":"Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if post.Status != postPublished {
+		http.Error(w, `{"error":"Only published posts can be commented on"}`, http.StatusConflict)
+		return
+	}
 	
 	comment, err := api.CreateComment(r.Context(), db.CreateCommentParams{
 		PostID: postID,
@@ -576,6 +781,8 @@ func Comment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
 		return
 	}
+
+	recordMentions(r.Context(), userID, postID, &comment.ID, comment.Content)
 	
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"id": comment.ID,
@@ -610,12 +817,23 @@ func Activity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := req.Username
 	limit := req.Limit
 	offset := req.Offset
+
+	// Links to a user who has since been renamed keep working.
+	user, err := resolveUsername(r.Context(), req.Username)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"Database error"}`, http.StatusInternalServerError)
+		return
+	}
 	
 	res, err := api.GetLatestUserActivity(r.Context(), db.GetLatestUserActivityParams{
-		Username: username,
+		Username: user.Username,
+		ViewerID: viewerID(),
 		Limit: limit,
 		Offset: offset,
 	})
@@ -626,6 +844,7 @@ func Activity(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": user.Username,
 		"activity": res.Activity,
 	})
 }
\ No newline at end of file